@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+// appTokenSource authenticates as a GitHub App installation: it signs a
+// short-lived JWT with the app's private key and exchanges it for an
+// installation token, which is cached on disk until shortly before it expires.
+type appTokenSource struct {
+	hc             *hostConfig
+	httpClient     *http.Client
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+}
+
+func newAppTokenSource(hc *hostConfig, httpClient *http.Client) (*appTokenSource, error) {
+	appID, err := strconv.ParseInt(hc.AppID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app id %q for %s: %v", hc.AppID, hc.Host, err)
+	}
+
+	var installationID int64
+	if hc.InstallationID != "" {
+		installationID, err = strconv.ParseInt(hc.InstallationID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid installation id %q for %s: %v", hc.InstallationID, hc.Host, err)
+		}
+	}
+
+	if hc.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("app id is set for %s but no private key file is configured", hc.Host)
+	}
+
+	content, err := ioutil.ReadFile(hc.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePrivateKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", hc.PrivateKeyFile, err)
+	}
+
+	return &appTokenSource{
+		hc:             hc,
+		httpClient:     httpClient,
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+	}, nil
+}
+
+func parsePrivateKey(content []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM data found in private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+func (ats *appTokenSource) jwt() (string, error) {
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	// iat is backdated to allow for clock drift; GitHub caps exp at 10 minutes.
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": ats.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(nil, ats.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+func (ats *appTokenSource) appClient() (*github.Client, error) {
+	jwt, err := ats.jwt()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, ats.httpClient)
+	httpClient := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt, TokenType: "Bearer"}))
+
+	return ats.hc.githubClient(httpClient)
+}
+
+func (ats *appTokenSource) cachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "barb", fmt.Sprintf("app-%s-%d-%d.json", ats.hc.Host, ats.appID, ats.installationID))
+}
+
+func (ats *appTokenSource) cached() *oauth2.Token {
+	path := ats.cachePath()
+	if path == "" || ats.installationID == 0 {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	token := &oauth2.Token{}
+	if err := json.Unmarshal(content, token); err != nil || !token.Valid() {
+		return nil
+	}
+
+	return token
+}
+
+func (ats *appTokenSource) store(token *oauth2.Token) {
+	path := ats.cachePath()
+	if path == "" {
+		return
+	}
+
+	content, err := json.Marshal(token)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	ioutil.WriteFile(path, content, 0600)
+}
+
+func (ats *appTokenSource) Token() (*oauth2.Token, error) {
+	if token := ats.cached(); token != nil {
+		return token, nil
+	}
+
+	client, err := ats.appClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if ats.installationID == 0 {
+		r, err := resolveRepo()
+		if err != nil {
+			return nil, fmt.Errorf("no installation id configured and %v", err)
+		}
+
+		inst, _, err := client.Apps.FindRepositoryInstallation(context.Background(), r.Owner, r.Name)
+		if err != nil {
+			return nil, fmt.Errorf("finding app installation for %s: %v", r, err)
+		}
+
+		ats.installationID = inst.GetID()
+		if token := ats.cached(); token != nil {
+			return token, nil
+		}
+	}
+
+	it, _, err := client.Apps.CreateInstallationToken(context.Background(), ats.installationID)
+	if err != nil {
+		return nil, fmt.Errorf("creating installation token: %v", err)
+	}
+
+	// refresh a minute early so in-flight requests never carry a stale token.
+	token := &oauth2.Token{
+		AccessToken: it.GetToken(),
+		TokenType:   "Bearer",
+		Expiry:      it.GetExpiresAt().Add(-time.Minute),
+	}
+
+	ats.store(token)
+	return token, nil
+}
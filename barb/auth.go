@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+var requiredScopes = []string{"repo"}
+
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+type deviceToken struct {
+	AccessToken string `json:"access_token"`
+	Scope       string `json:"scope"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+	Interval    int    `json:"interval"`
+}
+
+func authHost(ctx *cli.Context) string {
+	if host := ctx.String("hostname"); host != "" {
+		return host
+	}
+
+	return currentHost()
+}
+
+func postForm(client *http.Client, endpoint string, form url.Values, v interface{}) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func deviceFlow(hc *hostConfig, scopes []string) (string, error) {
+	if hc.ClientID == "" {
+		return "", fmt.Errorf("no OAuth client id for %s; set client_id for the host in the config file, BARB_OAUTH_CLIENT_ID, or pass --client-id", hc.Host)
+	}
+
+	httpClient, err := hc.httpClient()
+	if err != nil {
+		return "", err
+	}
+
+	base := "https://" + hc.Host + "/login"
+
+	code := &deviceCode{}
+	err = postForm(httpClient, base+"/device/code", url.Values{
+		"client_id": {hc.ClientID},
+		"scope":     {strings.Join(scopes, " ")},
+	}, code)
+	if err != nil {
+		return "", err
+	}
+
+	color.New(color.FgHiWhite).Printf("First copy your one-time code: %s\n", code.UserCode)
+	fmt.Printf("Then open %s in your browser and paste it.\n", code.VerificationURI)
+
+	interval := time.Duration(code.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		token := &deviceToken{}
+		err := postForm(httpClient, base+"/oauth/access_token", url.Values{
+			"client_id":   {hc.ClientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, token)
+		if err != nil {
+			return "", err
+		}
+
+		switch token.Error {
+		case "":
+			return token.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval = time.Duration(token.Interval) * time.Second
+			if interval == 0 {
+				interval = time.Duration(code.Interval+5) * time.Second
+			}
+		default:
+			return "", fmt.Errorf("login failed: %s", firstNonEmpty(token.Description, token.Error))
+		}
+	}
+
+	return "", errors.New("login timed out; the device code expired")
+}
+
+func tokenScopes(hc *hostConfig) (string, []string, error) {
+	client, err := newClient(hc)
+	if err != nil {
+		return "", nil, err
+	}
+
+	user, resp, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	// fine-grained and app tokens carry no scopes header at all.
+	if _, ok := resp.Header["X-Oauth-Scopes"]; !ok {
+		return user.GetLogin(), nil, nil
+	}
+
+	scopes := []string{}
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return user.GetLogin(), scopes, nil
+}
+
+func missingScopes(have []string) []string {
+	missing := []string{}
+
+	if have == nil {
+		return missing
+	}
+
+	for _, want := range requiredScopes {
+		found := false
+		for _, scope := range have {
+			if scope == want {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+
+	return missing
+}
+
+func authLogin(ctx *cli.Context) {
+	host := authHost(ctx)
+	hc := lookupHost(host)
+
+	if id := ctx.String("client-id"); id != "" {
+		hc.ClientID = id
+	}
+
+	var (
+		token string
+		err   error
+	)
+
+	if ctx.Bool("with-token") {
+		token, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && token == "" {
+			exitError(fmt.Errorf("reading token from stdin: %v", err))
+		}
+
+		token = strings.TrimSpace(token)
+	} else {
+		scopes := append([]string{}, requiredScopes...)
+		scopes = append(scopes, ctx.StringSlice("scope")...)
+
+		token, err = deviceFlow(hc, scopes)
+		if err != nil {
+			exitError(err)
+		}
+	}
+
+	hc.Token = token
+	login, scopes, err := tokenScopes(hc)
+	if err != nil {
+		exitError(fmt.Errorf("validating token: %v", err))
+	}
+
+	if missing := missingScopes(scopes); len(missing) > 0 {
+		exitError(fmt.Errorf("token is missing required scopes: %s", strings.Join(missing, ", ")))
+	}
+
+	stores := tokenStores()
+	var stored tokenStore
+
+	for _, store := range stores {
+		if err = store.Set(host, token); err == nil {
+			stored = store
+			break
+		}
+
+		fmt.Fprintf(os.Stderr, "warning: %s: %v\n", store.Name(), err)
+	}
+
+	if stored == nil {
+		exitError(errors.New("could not store the token anywhere"))
+	}
+
+	color.New(color.FgGreen).Printf("Logged in to %s as %s (token stored in %s)\n", host, login, stored.Name())
+}
+
+func authLogout(ctx *cli.Context) {
+	host := authHost(ctx)
+	removed := false
+
+	for _, store := range tokenStores() {
+		if _, err := store.Get(host); err == errTokenNotFound {
+			continue
+		} else if err != nil {
+			exitError(fmt.Errorf("%s: %v", store.Name(), err))
+		}
+
+		if err := store.Delete(host); err != nil {
+			exitError(err)
+		}
+
+		removed = true
+	}
+
+	if !removed {
+		exitError(fmt.Errorf("not logged in to %s", host))
+	}
+
+	fmt.Printf("Logged out of %s\n", host)
+}
+
+func authStatus(ctx *cli.Context) {
+	host := authHost(ctx)
+	hc := lookupHost(host)
+
+	if hc.AppID != "" {
+		authAppStatus(hc)
+		return
+	}
+
+	if hc.Token == "" {
+		exitError(fmt.Errorf("not logged in to %s; run `barb auth login`", host))
+	}
+
+	login, scopes, err := tokenScopes(hc)
+	if err != nil {
+		exitError(fmt.Errorf("token from %s is not valid for %s: %v", hc.TokenSource, host, err))
+	}
+
+	color.New(color.FgGreen).Printf("Logged in to %s as %s\n", host, login)
+	fmt.Printf("Token source: %s\n", hc.TokenSource)
+	if scopes != nil {
+		fmt.Printf("Token scopes: %s\n", strings.Join(scopes, ", "))
+	}
+
+	if missing := missingScopes(scopes); len(missing) > 0 {
+		color.New(color.FgYellow).Printf("Missing scopes: %s\n", strings.Join(missing, ", "))
+	}
+}
+
+func authAppStatus(hc *hostConfig) {
+	httpClient, err := hc.uncachedHTTPClient()
+	if err != nil {
+		exitError(err)
+	}
+
+	ts, err := newAppTokenSource(hc, httpClient)
+	if err != nil {
+		exitError(err)
+	}
+
+	token, err := ts.Token()
+	if err != nil {
+		exitError(err)
+	}
+
+	color.New(color.FgGreen).Printf("Authenticated to %s as GitHub App %d (installation %d)\n", hc.Host, ts.appID, ts.installationID)
+	fmt.Printf("Installation token expires: %v\n", token.Expiry.Local())
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var cacheDisabled bool
+
+func cacheDir() string {
+	if dir := currentConfig.Cache.Dir; dir != "" {
+		if strings.HasPrefix(dir, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				dir = filepath.Join(home, dir[2:])
+			}
+		}
+
+		return dir
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "barb", "http")
+}
+
+func cacheTTL() time.Duration {
+	if currentConfig.Cache.TTL == "" {
+		return 0
+	}
+
+	ttl, err := time.ParseDuration(currentConfig.Cache.TTL)
+	if err != nil {
+		return 0
+	}
+
+	return ttl
+}
+
+// cacheTransport keeps GET responses on disk and revalidates them with
+// If-None-Match/If-Modified-Since; GitHub does not count 304 responses
+// against the rate limit. Responses younger than the configured TTL are
+// served without touching the network at all. Requests sent with
+// Cache-Control: no-store skip the cache.
+type cacheTransport struct {
+	base http.RoundTripper
+	dir  string
+	ttl  time.Duration
+}
+
+func newCacheTransport(base http.RoundTripper) http.RoundTripper {
+	dir := cacheDir()
+	if cacheDisabled || dir == "" {
+		return base
+	}
+
+	return &cacheTransport{base: base, dir: dir, ttl: cacheTTL()}
+}
+
+func (ct *cacheTransport) path(req *http.Request) string {
+	// the credential is part of the key so accounts never see each other's data.
+	sum := sha256.New()
+	for _, part := range []string{req.URL.String(), req.Header.Get("Authorization"), req.Header.Get("Accept")} {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+
+	key := hex.EncodeToString(sum.Sum(nil))
+	return filepath.Join(ct.dir, key[:2], key)
+}
+
+func (ct *cacheTransport) load(path string, req *http.Request) (*http.Response, time.Time) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(content)), req)
+	if err != nil {
+		os.Remove(path)
+		return nil, time.Time{}
+	}
+
+	return resp, fi.ModTime()
+}
+
+func (ct *cacheTransport) store(path string, resp *http.Response) (*http.Response, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	stored := *resp
+	stored.Body = ioutil.NopCloser(bytes.NewReader(body))
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+
+	var buf bytes.Buffer
+	if err := stored.Write(&buf); err != nil {
+		return resp, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return resp, nil
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return resp, nil
+	}
+
+	os.Rename(tmp, path)
+	return resp, nil
+}
+
+func (ct *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" || req.Header.Get("Range") != "" || strings.Contains(req.Header.Get("Cache-Control"), "no-store") {
+		return ct.base.RoundTrip(req)
+	}
+
+	path := ct.path(req)
+	cached, storedAt := ct.load(path, req)
+
+	if cached != nil && ct.ttl > 0 && time.Since(storedAt) < ct.ttl {
+		// the stored quota headers are stale and would mislead go-github.
+		stripRateHeaders(cached.Header)
+		return cached, nil
+	}
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := ct.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+
+		now := time.Now()
+		os.Chtimes(path, now, now)
+
+		stripRateHeaders(cached.Header)
+		for key, values := range resp.Header {
+			if strings.HasPrefix(key, "X-Ratelimit-") {
+				cached.Header[key] = values
+			}
+		}
+
+		return cached, nil
+	}
+
+	if resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "") {
+		return ct.store(path, resp)
+	}
+
+	return resp, nil
+}
+
+func stripRateHeaders(header http.Header) {
+	for _, key := range []string{"X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset", "X-Ratelimit-Used", "X-Ratelimit-Resource"} {
+		header.Del(key)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// etagServer answers with an ETag for the Authorization and Accept headers
+// it was sent, and with 304 when the client already has that version.
+type etagServer struct {
+	mutex    sync.Mutex
+	requests []http.Header
+}
+
+func (es *etagServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	es.mutex.Lock()
+	es.requests = append(es.requests, r.Header.Clone())
+	es.mutex.Unlock()
+
+	etag := `"` + r.Header.Get("Authorization") + "|" + r.Header.Get("Accept") + `"`
+	w.Header().Set("X-RateLimit-Remaining", "4000")
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	io.WriteString(w, "body for "+etag)
+}
+
+func (es *etagServer) sent() []http.Header {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	return append([]http.Header(nil), es.requests...)
+}
+
+func cacheGet(t *testing.T, rt http.RoundTripper, url string, headers ...string) (*http.Response, string) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", url, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, string(body)
+}
+
+func newTestCache(t *testing.T, ttl time.Duration) (*etagServer, *cacheTransport, string) {
+	es := &etagServer{}
+	server := httptest.NewServer(es)
+	t.Cleanup(server.Close)
+
+	return es, &cacheTransport{base: http.DefaultTransport, dir: t.TempDir(), ttl: ttl}, server.URL + "/repos/o/r"
+}
+
+func TestCacheRevalidates(t *testing.T) {
+	es, ct, url := newTestCache(t, 0)
+
+	_, first := cacheGet(t, ct, url, "Authorization", "token a")
+	resp, second := cacheGet(t, ct, url, "Authorization", "token a")
+
+	if resp.StatusCode != http.StatusOK || second != first {
+		t.Fatalf("got %d %q, want the stored 200 %q", resp.StatusCode, second, first)
+	}
+
+	sent := es.sent()
+	if len(sent) != 2 || sent[1].Get("If-None-Match") != `"token a|"` {
+		t.Fatalf("got requests %v, want a revalidation with the stored ETag", sent)
+	}
+
+	// the quota comes from the 304, not from the stored response.
+	if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "4000" {
+		t.Errorf("got X-RateLimit-Remaining %q, want the 304's", remaining)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	es, ct, url := newTestCache(t, 0)
+
+	for _, test := range []struct {
+		headers []string
+		want    string
+	}{
+		{[]string{"Authorization", "token a"}, `body for "token a|"`},
+		{[]string{"Authorization", "token b"}, `body for "token b|"`},
+		{[]string{"Authorization", "token a", "Accept", "application/vnd.github.v3.diff"}, `body for "token a|application/vnd.github.v3.diff"`},
+	} {
+		if _, body := cacheGet(t, ct, url, test.headers...); body != test.want {
+			t.Errorf("%v: got %q, want %q", test.headers, body, test.want)
+		}
+	}
+
+	for i, header := range es.sent() {
+		if etag := header.Get("If-None-Match"); etag != "" {
+			t.Errorf("request %d was revalidated with %s, which belongs to other headers", i, etag)
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	es, ct, url := newTestCache(t, time.Hour)
+
+	cacheGet(t, ct, url)
+	resp, body := cacheGet(t, ct, url)
+
+	if n := len(es.sent()); n != 1 || body != `body for "|"` {
+		t.Fatalf("got %q after %d requests, want the stored body after 1", body, n)
+	}
+
+	// the stored quota would be stale.
+	if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+		t.Errorf("got X-RateLimit-Remaining %q from the cache", remaining)
+	}
+}
+
+func TestCacheNoStore(t *testing.T) {
+	es, ct, url := newTestCache(t, time.Hour)
+
+	cacheGet(t, ct, url, "Cache-Control", "no-store")
+	cacheGet(t, ct, url, "Cache-Control", "no-store")
+	cacheGet(t, ct, url)
+
+	for i, header := range es.sent() {
+		if etag := header.Get("If-None-Match"); etag != "" {
+			t.Errorf("request %d was revalidated with %s", i, etag)
+		}
+	}
+
+	if n := len(es.sent()); n != 3 {
+		t.Fatalf("got %d requests, want 3 with nothing stored", n)
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	saved := *currentConfig
+	t.Cleanup(func() {
+		*currentConfig = saved
+		cacheDisabled = false
+	})
+
+	currentConfig.Cache = cacheSettings{Dir: t.TempDir(), TTL: "10m"}
+
+	ct, ok := newCacheTransport(http.DefaultTransport).(*cacheTransport)
+	if !ok || ct.dir != currentConfig.Cache.Dir || ct.ttl != 10*time.Minute {
+		t.Fatalf("got %#v, want a cache in the configured dir with a 10m TTL", ct)
+	}
+
+	cacheDisabled = true
+	if rt := newCacheTransport(http.DefaultTransport); rt != http.DefaultTransport {
+		t.Fatalf("got %#v with --no-cache, want the transport as is", rt)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+// checkoutBranch names the local branch for a pull request: its head branch,
+// prefixed with the fork owner when that would shadow the base branch.
+func checkoutBranch(pr *github.PullRequest, fork bool) string {
+	name := pr.GetHead().GetRef()
+
+	if name == "" {
+		return fmt.Sprintf("pr-%d", pr.GetNumber())
+	}
+
+	if fork && (name == pr.GetBase().GetRef() || name == pr.GetBase().GetRepo().GetDefaultBranch()) {
+		// a deleted fork has no owner to prefix with.
+		owner := pr.GetHead().GetRepo().GetOwner().GetLogin()
+		if owner == "" {
+			return fmt.Sprintf("pr-%d", pr.GetNumber())
+		}
+
+		return owner + "/" + name
+	}
+
+	return name
+}
+
+func branchExists(name string) bool {
+	return exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+name).Run() == nil
+}
+
+// ensureRemote returns a remote for the fork the pull request comes from,
+// adding one named after its owner when none is configured. The URL uses
+// the same scheme as base's remote so pushing works with the same keys.
+func ensureRemote(r *repository, base string, head *github.Repository) (string, error) {
+	fork := &repository{Host: r.Host, Owner: head.Owner.GetLogin(), Name: head.GetName()}
+	if remote := findRemote(fork); remote != "" {
+		return remote, nil
+	}
+
+	url := head.GetCloneURL()
+	if strings.HasPrefix(gitConfig("remote."+base+".url"), "git@") || strings.HasPrefix(gitConfig("remote."+base+".url"), "ssh://") {
+		url = head.GetSSHURL()
+	}
+
+	name := fork.Owner
+	if gitConfig("remote."+name+".url") != "" {
+		return "", fmt.Errorf("a remote named %q already exists but does not point at %s", name, fork)
+	}
+
+	if err := runGit("remote", "add", name, url); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+func checkoutPR(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil {
+		exitError(err)
+	}
+
+	if gitTopLevel() == "" {
+		exitError(errors.New("pr checkout must be run inside a git repository"))
+	}
+
+	worktree := ctx.String("worktree")
+
+	// a new worktree leaves the current checkout alone.
+	if worktree == "" {
+		status, err := gitOutput("status", "--porcelain", "--untracked-files=no")
+		if err != nil {
+			exitError(err)
+		}
+
+		if status != "" {
+			exitError(errors.New("you have uncommitted changes; commit or stash them, or use --worktree"))
+		}
+	}
+
+	r, err := resolveRepo()
+	if err != nil {
+		exitError(err)
+	}
+
+	client := getClient()
+
+	pr, _, err := client.PullRequests.Get(context.Background(), r.Owner, r.Name, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	base := findRemote(r)
+	if base == "" {
+		base = pr.Base.Repo.GetCloneURL()
+	}
+
+	// a deleted fork leaves Head.Repo empty; only the pull ref remains.
+	head := pr.Head.Repo
+	fork := head == nil || head.GetFullName() != pr.Base.Repo.GetFullName()
+
+	branch := ctx.String("branch")
+	if branch == "" {
+		branch = checkoutBranch(pr, fork)
+	}
+
+	var remote, merge string
+
+	switch {
+	case !fork || (head != nil && pr.GetMaintainerCanModify()):
+		// track the real branch, so pushing updates the pull request.
+		remote = base
+		if fork {
+			if remote, err = ensureRemote(r, base, head); err != nil {
+				exitError(err)
+			}
+		}
+
+		merge = "refs/heads/" + pr.Head.GetRef()
+	default:
+		remote = base
+		merge = fmt.Sprintf("refs/pull/%d/head", num)
+	}
+
+	if err := runGit("fetch", remote, merge); err != nil {
+		exitError(err)
+	}
+
+	// FETCH_HEAD is per worktree, so pin the commit before adding one.
+	start, err := gitOutput("rev-parse", "FETCH_HEAD")
+	if err != nil {
+		exitError(err)
+	}
+
+	exists := branchExists(branch)
+
+	if exists {
+		if tracked := gitConfig("branch." + branch + ".merge"); tracked != "" && tracked != merge {
+			exitError(fmt.Errorf("branch %s already exists and tracks %s; pick another name with --branch", branch, tracked))
+		}
+	} else {
+		if err := runGit("branch", "--no-track", branch, start); err != nil {
+			exitError(err)
+		}
+
+		if err := runGit("config", "branch."+branch+".remote", remote); err != nil {
+			exitError(err)
+		}
+
+		if err := runGit("config", "branch."+branch+".merge", merge); err != nil {
+			exitError(err)
+		}
+	}
+
+	dir := ""
+	if worktree != "" {
+		if err := runGit("worktree", "add", worktree, branch); err != nil {
+			exitError(err)
+		}
+
+		dir = worktree
+	} else if err := runGit("checkout", branch); err != nil {
+		exitError(err)
+	}
+
+	// an existing branch is brought up to date, but never rewritten.
+	if exists {
+		if err := runGit("-C", firstNonEmpty(dir, "."), "merge", "--ff-only", start); err != nil {
+			exitError(err)
+		}
+	}
+
+	text := fmt.Sprintf("Checked out PR %d as %s", num, branch)
+	if dir != "" {
+		text += " in " + dir
+	}
+
+	printResult(ctx, resultView{Number: num, Action: "checked out", URL: pr.GetHTMLURL()}, text+".")
+}
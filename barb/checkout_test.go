@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestCheckoutBranch(t *testing.T) {
+	repo := func(owner string) *github.Repository {
+		return &github.Repository{Owner: &github.User{Login: github.String(owner)}, DefaultBranch: github.String("master")}
+	}
+
+	for _, test := range []struct {
+		head *github.PullRequestBranch
+		fork bool
+		want string
+	}{
+		{&github.PullRequestBranch{Ref: github.String("topic"), Repo: repo("o")}, false, "topic"},
+		{&github.PullRequestBranch{Ref: github.String("topic"), Repo: repo("alice")}, true, "topic"},
+		{&github.PullRequestBranch{Ref: github.String("master"), Repo: repo("alice")}, true, "alice/master"},
+		{&github.PullRequestBranch{Ref: github.String("main"), Repo: repo("alice")}, true, "alice/main"},
+		{&github.PullRequestBranch{}, true, "pr-7"},
+		// a deleted fork leaves no head repository.
+		{&github.PullRequestBranch{Ref: github.String("topic")}, true, "topic"},
+		{&github.PullRequestBranch{Ref: github.String("master")}, true, "pr-7"},
+	} {
+		pr := &github.PullRequest{
+			Number: github.Int(7),
+			Head:   test.head,
+			Base:   &github.PullRequestBranch{Ref: github.String("main"), Repo: repo("o")},
+		}
+
+		if got := checkoutBranch(pr, test.fork); got != test.want {
+			t.Errorf("head %s of %v, fork %v: got %q, want %q", test.head.GetRef(), test.head.GetRepo().GetOwner().GetLogin(), test.fork, got, test.want)
+		}
+	}
+}
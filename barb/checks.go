@@ -0,0 +1,475 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+// checkView is a commit status or a check run; most of barb does not care
+// which API a CI system reports through.
+type checkView struct {
+	Name        string     `json:"name" yaml:"name"`
+	Kind        string     `json:"kind" yaml:"kind"`
+	State       string     `json:"state" yaml:"state"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	URL         string     `json:"url,omitempty" yaml:"url,omitempty"`
+	App         string     `json:"app,omitempty" yaml:"app,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty" yaml:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" yaml:"completed_at,omitempty"`
+
+	Annotations []annotationView `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+
+	id    int64
+	suite int64
+}
+
+type annotationView struct {
+	Path      string `json:"path" yaml:"path"`
+	StartLine int    `json:"start_line" yaml:"start_line"`
+	EndLine   int    `json:"end_line" yaml:"end_line"`
+	Level     string `json:"level" yaml:"level"`
+	Title     string `json:"title,omitempty" yaml:"title,omitempty"`
+	Message   string `json:"message" yaml:"message"`
+}
+
+// stateClass reduces a status state or check run conclusion to pending,
+// success or failure. Having no checks at all counts as success.
+func stateClass(state string) string {
+	switch state {
+	case "pending", "queued", "in_progress", "waiting", "requested":
+		return "pending"
+	case "success", "neutral", "skipped", "none":
+		return "success"
+	}
+
+	return "failure"
+}
+
+func timestampTime(t *github.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func newCheckRunView(run *github.CheckRun) checkView {
+	state := run.GetConclusion()
+	if run.GetStatus() != "completed" {
+		state = run.GetStatus()
+	}
+
+	return checkView{
+		Name:        run.GetName(),
+		Kind:        "check",
+		State:       state,
+		Description: run.GetOutput().GetTitle(),
+		URL:         run.GetHTMLURL(),
+		App:         run.GetApp().GetName(),
+		StartedAt:   timestampTime(run.StartedAt),
+		CompletedAt: timestampTime(run.CompletedAt),
+		id:          run.GetID(),
+		suite:       run.GetCheckSuite().GetID(),
+	}
+}
+
+// checkRuns lists the check runs for ref. Enterprise releases without the
+// Checks API answer 404, which is the same as having none.
+func checkRuns(ctx context.Context, client *github.Client, owner, repo, ref string) ([]*github.CheckRun, error) {
+	runs := []*github.CheckRun{}
+
+	err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		result, resp, err := client.Checks.ListCheckRunsForRef(ctx, owner, repo, ref, &github.ListCheckRunsOptions{
+			ListOptions: opts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, result.CheckRuns...)
+		return resp, nil
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return runs, nil
+		}
+
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// headChecks returns every commit status and check run reported on sha.
+func headChecks(ctx context.Context, client *github.Client, owner, repo, sha string) ([]checkView, error) {
+	checks := []checkView{}
+
+	err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		status, resp, err := client.Repositories.GetCombinedStatus(ctx, owner, repo, sha, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range status.Statuses {
+			checks = append(checks, checkView{
+				Name:        s.GetContext(),
+				Kind:        "status",
+				State:       s.GetState(),
+				Description: s.GetDescription(),
+				URL:         s.GetTargetURL(),
+				StartedAt:   s.CreatedAt,
+				CompletedAt: s.UpdatedAt,
+			})
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := checkRuns(ctx, client, owner, repo, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		checks = append(checks, newCheckRunView(run))
+	}
+
+	return checks, nil
+}
+
+// overallState is failure if anything failed, pending if anything is still
+// running, success otherwise, and none when nothing reported at all.
+func overallState(checks []checkView) string {
+	if len(checks) == 0 {
+		return "none"
+	}
+
+	state := "success"
+
+	for _, check := range checks {
+		switch stateClass(check.State) {
+		case "failure":
+			return "failure"
+		case "pending":
+			state = "pending"
+		}
+	}
+
+	return state
+}
+
+// duration is how long a check ran, or has been running so far.
+func (cv checkView) duration() time.Duration {
+	if cv.StartedAt == nil {
+		return 0
+	}
+
+	end := time.Now()
+	if cv.CompletedAt != nil && stateClass(cv.State) != "pending" {
+		end = *cv.CompletedAt
+	}
+
+	return end.Sub(*cv.StartedAt).Round(time.Second)
+}
+
+func checkAnnotations(client *github.Client, owner, repo string, id int64) ([]annotationView, error) {
+	annotations := []annotationView{}
+
+	err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		list, resp, err := client.Checks.ListCheckRunAnnotations(context.Background(), owner, repo, id, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range list {
+			annotations = append(annotations, annotationView{
+				Path:      a.GetFileName(),
+				StartLine: a.GetStartLine(),
+				EndLine:   a.GetEndLine(),
+				Level:     a.GetWarningLevel(),
+				Title:     a.GetTitle(),
+				Message:   a.GetMessage(),
+			})
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
+
+// suiteConclusions maps check suite IDs to their status or conclusion.
+func suiteConclusions(client *github.Client, owner, repo, ref string) (map[int64]string, error) {
+	suites := map[int64]string{}
+
+	err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		result, resp, err := client.Checks.ListCheckSuitesForRef(context.Background(), owner, repo, ref, &github.ListCheckSuiteOptions{
+			ListOptions: opts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, suite := range result.CheckSuites {
+			state := suite.GetConclusion()
+			if suite.GetStatus() != "completed" {
+				state = suite.GetStatus()
+			}
+
+			suites[suite.GetID()] = state
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return suites, nil
+		}
+
+		return nil, err
+	}
+
+	return suites, nil
+}
+
+// jobLog downloads the log of a GitHub Actions job; a check run created by
+// Actions has the same ID as its job. The API answers with a redirect to
+// short-lived storage that must not be sent our credentials.
+func jobLog(hc *hostConfig, client *github.Client, owner, repo string, id int64) (io.ReadCloser, error) {
+	authed, err := authHTTPClient(hc)
+	if err != nil {
+		return nil, err
+	}
+
+	noRedirect := *authed
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/actions/jobs/%d/logs", owner, repo, id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// a log can be large and is read once; the cache must not copy it.
+	req.Header.Set("Cache-Control", "no-store")
+
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusFound, http.StatusTemporaryRedirect:
+		resp.Body.Close()
+	default:
+		defer resp.Body.Close()
+		return nil, github.CheckResponse(resp)
+	}
+
+	plain, err := hc.uncachedHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = plain.Get(resp.Header.Get("Location"))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading log: %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// findCheck picks a check run by ID or name, preferring a failed run when
+// several share the name.
+func findCheck(checks []checkView, spec string) (*checkView, error) {
+	var found *checkView
+
+	for i, check := range checks {
+		if check.Kind != "check" {
+			continue
+		}
+
+		if strconv.FormatInt(check.id, 10) == spec {
+			return &checks[i], nil
+		}
+
+		if check.Name == spec && (found == nil || stateClass(check.State) == "failure") {
+			found = &checks[i]
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no check run named %q", spec)
+	}
+
+	return found, nil
+}
+
+func checksPR(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil {
+		exitError(err)
+	}
+
+	owner, repo, err := repo()
+	if err != nil {
+		exitError(err)
+	}
+
+	hc := lookupHost(currentHost())
+	client, err := newClient(hc)
+	if err != nil {
+		exitError(err)
+	}
+
+	pr, _, err := client.PullRequests.Get(context.Background(), owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	checks, err := headChecks(context.Background(), client, owner, repo, pr.Head.GetSHA())
+	if err != nil {
+		exitError(err)
+	}
+
+	if spec := ctx.String("log"); spec != "" {
+		check, err := findCheck(checks, spec)
+		if err != nil {
+			exitError(err)
+		}
+
+		if check.App != "GitHub Actions" {
+			exitError(fmt.Errorf("logs are only available for GitHub Actions jobs; see %s", check.URL))
+		}
+
+		log, err := jobLog(hc, client, owner, repo, check.id)
+		if err != nil {
+			exitError(err)
+		}
+		defer log.Close()
+
+		if err := page(log); err != nil {
+			exitError(err)
+		}
+
+		return
+	}
+
+	for i := range checks {
+		if checks[i].Kind == "check" && stateClass(checks[i].State) == "failure" {
+			checks[i].Annotations, err = checkAnnotations(client, owner, repo, checks[i].id)
+			if err != nil {
+				exitError(err)
+			}
+		}
+	}
+
+	if structuredOutput(ctx) {
+		printStructured(ctx, checks)
+		return
+	}
+
+	suites, err := suiteConclusions(client, owner, repo, pr.Head.GetSHA())
+	if err != nil {
+		exitError(err)
+	}
+
+	printChecks(checks, suites)
+}
+
+func printChecks(checks []checkView, suites map[int64]string) {
+	color.Output = os.Stdout
+
+	if len(checks) == 0 {
+		fmt.Println("No statuses or check runs reported.")
+		return
+	}
+
+	// statuses have no suite, so they sort first.
+	sort.SliceStable(checks, func(i, j int) bool {
+		return checks[i].suite < checks[j].suite
+	})
+
+	group := int64(-1)
+
+	for _, check := range checks {
+		suite := check.suite
+
+		if suite != group {
+			group = suite
+
+			if suite == 0 {
+				color.New(color.FgWhite, color.Bold).Println("Statuses")
+			} else {
+				_, c := stateMark(suites[suite])
+				color.New(color.FgWhite, color.Bold).Printf("%s ", check.App)
+				c.Printf("(%s)\n", firstNonEmpty(suites[suite], "unknown"))
+			}
+		}
+
+		mark, c := stateMark(check.State)
+		c.Printf("  %s %-30s %-12s", mark, check.Name, check.State)
+
+		if d := check.duration(); d > 0 {
+			fmt.Printf(" %8v", d)
+		}
+
+		if check.URL != "" {
+			fmt.Printf("  %s", check.URL)
+		}
+
+		fmt.Println()
+
+		for _, a := range check.Annotations {
+			level := color.New(color.FgYellow)
+			if a.Level == "failure" {
+				level = color.New(color.FgRed)
+			}
+
+			location := fmt.Sprintf("%s:%d", a.Path, a.StartLine)
+			if a.EndLine > a.StartLine {
+				location += fmt.Sprintf("-%d", a.EndLine)
+			}
+
+			level.Printf("      %s %s: ", location, a.Level)
+			fmt.Println(firstNonEmpty(a.Title, a.Message))
+
+			if a.Title != "" && a.Message != "" {
+				for _, l := range strings.Split(a.Message, "\n") {
+					fmt.Println("        " + l)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStateClass(t *testing.T) {
+	for state, want := range map[string]string{
+		"queued":          "pending",
+		"in_progress":     "pending",
+		"waiting":         "pending",
+		"requested":       "pending",
+		"pending":         "pending",
+		"success":         "success",
+		"neutral":         "success",
+		"skipped":         "success",
+		"failure":         "failure",
+		"error":           "failure",
+		"cancelled":       "failure",
+		"timed_out":       "failure",
+		"action_required": "failure",
+	} {
+		if got := stateClass(state); got != want {
+			t.Errorf("stateClass(%q) = %q, want %q", state, got, want)
+		}
+	}
+
+	// a job waiting on a deployment approval has not failed.
+	if got := overallState([]checkView{{State: "success"}, {State: "waiting"}}); got != "pending" {
+		t.Errorf("got overall state %q, want pending", got)
+	}
+}
+
+func TestCheckViewTimes(t *testing.T) {
+	queued := checkView{Name: "build", State: "queued"}
+
+	out, err := json.Marshal(queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(out), "_at") {
+		t.Errorf("got %s, want no times for a check that has not started", out)
+	}
+
+	if d := queued.duration(); d != 0 {
+		t.Errorf("got duration %v for a check that has not started", d)
+	}
+
+	started := time.Now().Add(-time.Hour)
+	completed := started.Add(90 * time.Second)
+	done := checkView{Name: "build", State: "success", StartedAt: &started, CompletedAt: &completed}
+
+	if d := done.duration(); d != 90*time.Second {
+		t.Errorf("got duration %v, want 1m30s", d)
+	}
+}
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// config is read from ~/.config/barb/config and then overlaid with
+// .barb.yml at the root of the current checkout, which may only set defaults
+// and aliases. A profile, chosen with --profile, $BARB_PROFILE or the
+// profile key, is overlaid last.
+//
+//	profile: work
+//	hosts:
+//	  github.com:
+//	    token: ...
+//	defaults:
+//	  pr create:
+//	    base: main
+//	  pr list:
+//	    max-pages: 10
+//	aliases:
+//	  mine: pr list --author @me
+//	cache:
+//	  dir: ~/.cache/barb/http
+//	  ttl: 30s
+//	profiles:
+//	  work:
+//	    hosts:
+//	      github.example.com:
+//	        token: ...
+//	        ca_file: /etc/ssl/corp-ca.pem
+type config struct {
+	Profile  string                       `yaml:"profile"`
+	Hosts    map[string]*hostSettings     `yaml:"hosts"`
+	Defaults map[string]map[string]string `yaml:"defaults"`
+	Aliases  map[string]string            `yaml:"aliases"`
+	Profiles map[string]*config           `yaml:"profiles"`
+	Cache    cacheSettings                `yaml:"cache"`
+}
+
+type cacheSettings struct {
+	Dir string `yaml:"dir"`
+	TTL string `yaml:"ttl"`
+}
+
+type hostSettings struct {
+	Token     string `yaml:"token"`
+	APIURL    string `yaml:"api_url"`
+	UploadURL string `yaml:"upload_url"`
+	CAFile    string `yaml:"ca_file"`
+	Proxy     string `yaml:"proxy"`
+	ClientID  string `yaml:"client_id"`
+
+	AppID          string `yaml:"app_id"`
+	InstallationID string `yaml:"installation_id"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+}
+
+var currentConfig = &config{}
+
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "barb")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".config", "barb")
+}
+
+func localConfigPath() string {
+	root := gitTopLevel()
+	if root == "" {
+		return ""
+	}
+
+	return filepath.Join(root, ".barb.yml")
+}
+
+func readConfig(path string) (*config, error) {
+	c := &config{}
+
+	if path == "" {
+		return c, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+
+	if c.Cache.TTL != "" {
+		if _, err := time.ParseDuration(c.Cache.TTL); err != nil {
+			return nil, fmt.Errorf("%s: invalid cache ttl: %v", path, err)
+		}
+	}
+
+	return c, nil
+}
+
+func loadConfig(profile string) (*config, error) {
+	c, err := readConfig(filepath.Join(configDir(), "config"))
+	if err != nil {
+		return nil, err
+	}
+
+	path := localConfigPath()
+
+	local, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := local.checkLocal(path); err != nil {
+		return nil, err
+	}
+
+	c.merge(local)
+
+	if profile == "" {
+		profile = os.Getenv("BARB_PROFILE")
+	}
+
+	if profile == "" {
+		profile = c.Profile
+	}
+
+	if profile != "" {
+		p, ok := c.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %q is not defined in the configuration", profile)
+		}
+
+		c.merge(p)
+		c.Profile = profile
+	}
+
+	return c, nil
+}
+
+// checkLocal rejects everything but defaults and aliases in a checkout's
+// .barb.yml. Any cloned repository could otherwise point a host's API URL or
+// proxy somewhere else and have the user's token sent there.
+func (c *config) checkLocal(path string) error {
+	keys := []string{}
+
+	if c.Profile != "" {
+		keys = append(keys, "profile")
+	}
+
+	if len(c.Hosts) > 0 {
+		keys = append(keys, "hosts")
+	}
+
+	if len(c.Profiles) > 0 {
+		keys = append(keys, "profiles")
+	}
+
+	if c.Cache != (cacheSettings{}) {
+		keys = append(keys, "cache")
+	}
+
+	if len(keys) > 0 {
+		return fmt.Errorf("%s: %s can only be set in %s", path, strings.Join(keys, ", "), filepath.Join(configDir(), "config"))
+	}
+
+	return nil
+}
+
+func (c *config) merge(other *config) {
+	if other.Profile != "" {
+		c.Profile = other.Profile
+	}
+
+	if other.Cache.Dir != "" {
+		c.Cache.Dir = other.Cache.Dir
+	}
+
+	if other.Cache.TTL != "" {
+		c.Cache.TTL = other.Cache.TTL
+	}
+
+	if c.Hosts == nil {
+		c.Hosts = map[string]*hostSettings{}
+	}
+
+	for host, settings := range other.Hosts {
+		if settings == nil {
+			continue
+		}
+
+		existing, ok := c.Hosts[host]
+		if !ok {
+			existing = &hostSettings{}
+			c.Hosts[host] = existing
+		}
+
+		existing.merge(settings)
+	}
+
+	if c.Defaults == nil {
+		c.Defaults = map[string]map[string]string{}
+	}
+
+	for command, values := range other.Defaults {
+		if c.Defaults[command] == nil {
+			c.Defaults[command] = map[string]string{}
+		}
+
+		for name, value := range values {
+			c.Defaults[command][name] = value
+		}
+	}
+
+	if c.Aliases == nil {
+		c.Aliases = map[string]string{}
+	}
+
+	for name, expansion := range other.Aliases {
+		c.Aliases[name] = expansion
+	}
+
+	if c.Profiles == nil {
+		c.Profiles = map[string]*config{}
+	}
+
+	for name, p := range other.Profiles {
+		c.Profiles[name] = p
+	}
+}
+
+func (hs *hostSettings) merge(other *hostSettings) {
+	if other.Token != "" {
+		hs.Token = other.Token
+	}
+
+	if other.APIURL != "" {
+		hs.APIURL = other.APIURL
+	}
+
+	if other.UploadURL != "" {
+		hs.UploadURL = other.UploadURL
+	}
+
+	if other.CAFile != "" {
+		hs.CAFile = other.CAFile
+	}
+
+	if other.Proxy != "" {
+		hs.Proxy = other.Proxy
+	}
+
+	if other.ClientID != "" {
+		hs.ClientID = other.ClientID
+	}
+
+	if other.AppID != "" {
+		hs.AppID = other.AppID
+	}
+
+	if other.InstallationID != "" {
+		hs.InstallationID = other.InstallationID
+	}
+
+	if other.PrivateKeyFile != "" {
+		hs.PrivateKeyFile = other.PrivateKeyFile
+	}
+}
+
+// profileFromArgs finds --profile before cli parsing, since the profile
+// decides which aliases exist and must be known before dispatch.
+func profileFromArgs(args []string) string {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+
+		if arg == "--profile" || arg == "-profile" {
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+		}
+
+		for _, prefix := range []string{"--profile=", "-profile="} {
+			if strings.HasPrefix(arg, prefix) {
+				return strings.TrimPrefix(arg, prefix)
+			}
+		}
+	}
+
+	return ""
+}
+
+func flagTakesValue(flags []cli.Flag, arg string) bool {
+	if strings.Contains(arg, "=") {
+		return false
+	}
+
+	name := strings.TrimLeft(arg, "-")
+
+	for _, flag := range flags {
+		if !hasFlagName(flag, name) {
+			continue
+		}
+
+		switch flag.(type) {
+		case cli.BoolFlag, cli.BoolTFlag:
+			return false
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandAlias replaces the first command word with its alias expansion.
+// Builtin commands always win over aliases of the same name.
+func expandAlias(app *cli.App, args []string, aliases map[string]string) ([]string, error) {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			return args, nil
+		}
+
+		if strings.HasPrefix(arg, "-") {
+			if flagTakesValue(app.Flags, arg) {
+				i++
+			}
+
+			continue
+		}
+
+		expansion, ok := aliases[arg]
+		if !ok || app.Command(arg) != nil {
+			return args, nil
+		}
+
+		words, err := splitWords(expansion)
+		if err != nil {
+			return nil, fmt.Errorf("alias %q: %v", arg, err)
+		}
+
+		expanded := append([]string{}, args[:i]...)
+		expanded = append(expanded, words...)
+		return append(expanded, args[i+1:]...), nil
+	}
+
+	return args, nil
+}
+
+// splitWords splits a command line on whitespace, honoring single and
+// double quotes and backslash escapes.
+func splitWords(s string) ([]string, error) {
+	words := []string{}
+
+	var (
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+func hasFlagName(flag cli.Flag, name string) bool {
+	for _, n := range strings.Split(flag.GetName(), ",") {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyDefaults overrides the default value of command flags with those in
+// the defaults section of the configuration, keyed by the full command path
+// ("pr list") and the long flag name.
+func applyDefaults(commands []cli.Command, prefix string, defaults map[string]map[string]string) error {
+	for i := range commands {
+		cmd := &commands[i]
+		path := strings.TrimSpace(prefix + " " + cmd.Name)
+
+		slices := map[string]string{}
+
+		for name, value := range defaults[path] {
+			if flag := sliceFlag(cmd.Flags, name); flag != nil {
+				slices[flag.GetName()] = value
+				continue
+			}
+
+			if err := setFlagDefault(cmd.Flags, name, value); err != nil {
+				return fmt.Errorf("defaults for %q: %v", path, err)
+			}
+		}
+
+		if len(slices) > 0 {
+			cmd.Before = sliceDefaults(cmd.Before, slices)
+		}
+
+		if err := applyDefaults(cmd.Subcommands, path, defaults); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setFlagDefault(flags []cli.Flag, name, value string) error {
+	for i, flag := range flags {
+		if !hasFlagName(flag, name) {
+			continue
+		}
+
+		switch f := flag.(type) {
+		case cli.StringFlag:
+			f.Value = value
+			flags[i] = f
+		case cli.IntFlag:
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("flag %q: %v", name, err)
+			}
+
+			f.Value = v
+			flags[i] = f
+		case cli.DurationFlag:
+			v, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("flag %q: %v", name, err)
+			}
+
+			f.Value = v
+			flags[i] = f
+		case cli.BoolFlag, cli.BoolTFlag:
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("flag %q: %v", name, err)
+			}
+
+			flags[i] = boolFlag(flag, v)
+		default:
+			return fmt.Errorf("flag %q cannot be given a default", name)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no such flag %q", name)
+}
+
+func sliceFlag(flags []cli.Flag, name string) cli.Flag {
+	for _, flag := range flags {
+		if _, ok := flag.(cli.StringSliceFlag); ok && hasFlagName(flag, name) {
+			return flag
+		}
+	}
+
+	return nil
+}
+
+// sliceDefaults fills in the defaults of slice flags, keyed by their full
+// names ("r, reviewer"), when the command line leaves them off.
+// StringSlice.Set appends to a flag's Value, so a default set there could
+// only be added to, never replaced.
+func sliceDefaults(before cli.BeforeFunc, defaults map[string]string) cli.BeforeFunc {
+	return func(ctx *cli.Context) error {
+		for names, value := range defaults {
+			if flagIsSet(ctx, names) {
+				continue
+			}
+
+			name := strings.TrimSpace(names[strings.LastIndex(names, ",")+1:])
+			for _, v := range strings.Split(value, ",") {
+				if err := ctx.Set(name, v); err != nil {
+					return fmt.Errorf("flag %q: %v", name, err)
+				}
+			}
+		}
+
+		if before != nil {
+			return before(ctx)
+		}
+
+		return nil
+	}
+}
+
+// flagIsSet is ctx.IsSet for any of a flag's names, which IsSet only
+// reports under the one given on the command line.
+func flagIsSet(ctx *cli.Context, names string) bool {
+	for _, name := range strings.Split(names, ",") {
+		if ctx.IsSet(strings.TrimSpace(name)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// boolFlag is flag as a BoolTFlag when it should default to true and as a
+// BoolFlag otherwise, keeping its usage, environment variable, visibility
+// and destination.
+func boolFlag(flag cli.Flag, value bool) cli.Flag {
+	var f cli.BoolFlag
+
+	switch flag := flag.(type) {
+	case cli.BoolFlag:
+		f = flag
+	case cli.BoolTFlag:
+		f = cli.BoolFlag(flag)
+	}
+
+	if value {
+		return cli.BoolTFlag(f)
+	}
+
+	return f
+}
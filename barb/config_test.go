@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), ".barb.yml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLocalConfigAllowed(t *testing.T) {
+	path := writeConfig(t, `
+defaults:
+  pr create:
+    base: main
+aliases:
+  mine: pr list --author @me
+`)
+
+	c, err := readConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.checkLocal(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalConfigRejected(t *testing.T) {
+	for content, key := range map[string]string{
+		"hosts:\n  github.com:\n    api_url: https://evil.example.com/\n":       "hosts",
+		"hosts:\n  github.com:\n    proxy: http://evil.example.com:3128\n":      "hosts",
+		"profiles:\n  work:\n    hosts:\n      github.com:\n        token: x\n": "profiles",
+		"profile: work\n":                 "profile",
+		"cache:\n  dir: /tmp/elsewhere\n": "cache",
+	} {
+		path := writeConfig(t, content)
+
+		c, err := readConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.checkLocal(path); err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("%q: got error %v, want one naming %s", content, err, key)
+		}
+	}
+}
+
+func TestBoolFlagDefaultKeepsFields(t *testing.T) {
+	var dest bool
+	flags := []cli.Flag{
+		cli.BoolFlag{Name: "d, draft", Usage: "Open as a draft", EnvVar: "BARB_DRAFT", Hidden: true, Destination: &dest},
+	}
+
+	if err := setFlagDefault(flags, "draft", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := cli.BoolTFlag{Name: "d, draft", Usage: "Open as a draft", EnvVar: "BARB_DRAFT", Hidden: true, Destination: &dest}
+	if flags[0] != want {
+		t.Fatalf("got %#v, want %#v", flags[0], want)
+	}
+
+	if err := setFlagDefault(flags, "draft", "false"); err != nil {
+		t.Fatal(err)
+	}
+
+	if flags[0] != cli.Flag(cli.BoolFlag(want)) {
+		t.Fatalf("got %#v, want it back as a BoolFlag", flags[0])
+	}
+}
+
+func TestSliceFlagDefault(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"alice", "bob"}},
+		{[]string{"--reviewer", "carol"}, []string{"carol"}},
+		{[]string{"-r", "carol", "-r", "dave"}, []string{"carol", "dave"}},
+	} {
+		var got []string
+
+		app := cli.NewApp()
+		app.Commands = []cli.Command{{
+			Name: "pr",
+			Subcommands: []cli.Command{{
+				Name:  "create",
+				Flags: []cli.Flag{cli.StringSliceFlag{Name: "r, reviewer"}},
+				Action: func(ctx *cli.Context) {
+					got = ctx.StringSlice("reviewer")
+				},
+			}},
+		}}
+
+		if err := applyDefaults(app.Commands, "", map[string]map[string]string{"pr create": {"reviewer": "alice,bob"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := app.Run(append([]string{"barb", "pr", "create"}, test.args...)); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got reviewers %v, want %v", test.args, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// diffLine is one line of a file's patch. Position is what the review
+// comments API calls the position: the line's offset from the first hunk
+// header, counting every later hunk header as a line too.
+type diffLine struct {
+	Position int
+	Kind     byte
+	OldLine  int
+	NewLine  int
+	Text     string
+}
+
+type diffHunk struct {
+	Header string
+	Lines  []diffLine
+}
+
+// parsePatch splits the patch GitHub returns for a single file into hunks,
+// numbering each line on both sides of the diff.
+func parsePatch(patch string) ([]diffHunk, error) {
+	hunks := []diffHunk{}
+	position := -1
+	oldLine, newLine := 0, 0
+
+	for _, text := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(text, "@@") {
+			var err error
+			oldLine, newLine, err = parseHunkHeader(text)
+			if err != nil {
+				return nil, err
+			}
+
+			position++
+			hunks = append(hunks, diffHunk{Header: text})
+			continue
+		}
+
+		if len(hunks) == 0 || text == "" {
+			continue
+		}
+
+		position++
+		dl := diffLine{Position: position, Kind: text[0], Text: text}
+
+		switch text[0] {
+		case '+':
+			dl.NewLine = newLine
+			newLine++
+		case '-':
+			dl.OldLine = oldLine
+			oldLine++
+		case '\\':
+			// "\ No newline at end of file" occupies a position but no line.
+		default:
+			dl.Kind = ' '
+			dl.OldLine = oldLine
+			dl.NewLine = newLine
+			oldLine++
+			newLine++
+		}
+
+		hunk := &hunks[len(hunks)-1]
+		hunk.Lines = append(hunk.Lines, dl)
+	}
+
+	return hunks, nil
+}
+
+// parseHunkHeader returns the starting old and new line numbers of a
+// "@@ -a,b +c,d @@" header.
+func parseHunkHeader(header string) (int, int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+
+	start := func(field string) (int, error) {
+		return strconv.Atoi(strings.SplitN(field[1:], ",", 2)[0])
+	}
+
+	oldStart, err := start(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+
+	newStart, err := start(fields[2])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+
+	return oldStart, newStart, nil
+}
+
+// diffPosition maps a line of the new version of a file to its position in
+// the patch, failing when the line is not part of any hunk.
+func diffPosition(file *github.CommitFile, line int) (int, error) {
+	if file.GetPatch() == "" {
+		return 0, fmt.Errorf("%s: no diff available (binary or too large)", file.GetFilename())
+	}
+
+	hunks, err := parsePatch(file.GetPatch())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", file.GetFilename(), err)
+	}
+
+	if _, dl := findDiffLine(hunks, "RIGHT", line); dl != nil {
+		return dl.Position, nil
+	}
+
+	return 0, fmt.Errorf("%s:%d is not part of the diff", file.GetFilename(), line)
+}
+
+// parseLocation splits a path:line or path:start-end argument. end equals
+// start for a single line.
+func parseLocation(arg string) (string, int, int, error) {
+	i := strings.LastIndex(arg, ":")
+	if i <= 0 {
+		return "", 0, 0, fmt.Errorf("invalid location %q; expected path:line or path:start-end", arg)
+	}
+
+	lines := strings.SplitN(arg[i+1:], "-", 2)
+
+	start, err := strconv.Atoi(lines[0])
+	if err != nil || start < 1 {
+		return "", 0, 0, fmt.Errorf("invalid line number in %q", arg)
+	}
+
+	end := start
+	if len(lines) == 2 {
+		end, err = strconv.Atoi(lines[1])
+		if err != nil || end < start {
+			return "", 0, 0, fmt.Errorf("invalid line range in %q", arg)
+		}
+	}
+
+	return arg[:i], start, end, nil
+}
+
+// parseFileLine is parseLocation for commands that take a single line.
+func parseFileLine(arg string) (string, int, error) {
+	path, start, end, err := parseLocation(arg)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if start != end {
+		return "", 0, fmt.Errorf("%q: line ranges are not supported here", arg)
+	}
+
+	return path, start, nil
+}
+
+// findDiffLine returns the hunk and line of the patch that carries line on
+// the given side; LEFT is the base version of the file and RIGHT the head.
+func findDiffLine(hunks []diffHunk, side string, line int) (int, *diffLine) {
+	// removed lines have no new line number and added ones no old one.
+	if line < 1 {
+		return -1, nil
+	}
+
+	for i, hunk := range hunks {
+		for j, dl := range hunk.Lines {
+			if (side == "LEFT" && dl.OldLine == line) || (side == "RIGHT" && dl.NewLine == line) {
+				return i, &hunks[i].Lines[j]
+			}
+		}
+	}
+
+	return -1, nil
+}
+
+// checkLineRange makes sure start through end on side are all in the same
+// hunk of the file's patch, which is what GitHub requires of a comment.
+func checkLineRange(file *github.CommitFile, side string, start, end int) error {
+	if file.GetPatch() == "" {
+		return fmt.Errorf("%s: no diff available (binary or too large)", file.GetFilename())
+	}
+
+	hunks, err := parsePatch(file.GetPatch())
+	if err != nil {
+		return fmt.Errorf("%s: %v", file.GetFilename(), err)
+	}
+
+	first, _ := findDiffLine(hunks, side, start)
+	if first < 0 {
+		return fmt.Errorf("%s:%d (%s) is not part of the diff", file.GetFilename(), start, strings.ToLower(side))
+	}
+
+	for line := start + 1; line <= end; line++ {
+		hunk, _ := findDiffLine(hunks, side, line)
+		if hunk != first {
+			return fmt.Errorf("%s:%d-%d (%s) does not fall within a single hunk", file.GetFilename(), start, end, strings.ToLower(side))
+		}
+	}
+
+	return nil
+}
+
+func pullFiles(client *github.Client, owner, repo string, num int) (map[string]*github.CommitFile, error) {
+	files := map[string]*github.CommitFile{}
+
+	err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		list, resp, err := client.PullRequests.ListFiles(context.Background(), owner, repo, num, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range list {
+			files[file.GetFilename()] = file
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
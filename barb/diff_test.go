@@ -0,0 +1,140 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+const testPatch = `@@ -1,3 +1,5 @@
+ package main
+-import "fmt"
++import (
++	"fmt"
++)
+ 
+@@ -10,2 +12,3 @@ func main() {
+ 	fmt.Println("a")
++	fmt.Println("b")
+ }
+\ No newline at end of file`
+
+func testFile(patch string) *github.CommitFile {
+	return &github.CommitFile{Filename: github.String("main.go"), Patch: github.String(patch)}
+}
+
+func TestParsePatch(t *testing.T) {
+	hunks, err := parsePatch(testPatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := []string{}
+	lines := [][4]int{}
+	for _, hunk := range hunks {
+		headers = append(headers, hunk.Header)
+		for _, dl := range hunk.Lines {
+			lines = append(lines, [4]int{dl.Position, int(dl.Kind), dl.OldLine, dl.NewLine})
+		}
+	}
+
+	if want := []string{"@@ -1,3 +1,5 @@", "@@ -10,2 +12,3 @@ func main() {"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("got hunks %q, want %q", headers, want)
+	}
+
+	// the second hunk header takes position 6.
+	want := [][4]int{
+		{1, ' ', 1, 1},
+		{2, '-', 2, 0},
+		{3, '+', 0, 2},
+		{4, '+', 0, 3},
+		{5, '+', 0, 4},
+		{6, ' ', 3, 5},
+		{8, ' ', 10, 12},
+		{9, '+', 0, 13},
+		{10, ' ', 11, 14},
+		{11, '\\', 0, 0},
+	}
+
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	for _, patch := range []string{"@@ broken @@\n+x", "@@ -a,1 +1,1 @@\n+x", "@@ -1,1 +b @@\n+x"} {
+		if _, err := parsePatch(patch); err == nil {
+			t.Errorf("%q: got no error", patch)
+		}
+	}
+}
+
+func TestDiffPosition(t *testing.T) {
+	for line, want := range map[int]int{1: 1, 2: 3, 4: 5, 5: 6, 12: 8, 13: 9, 14: 10} {
+		got, err := diffPosition(testFile(testPatch), line)
+		if err != nil || got != want {
+			t.Errorf("line %d: got position %d and error %v, want %d", line, got, err, want)
+		}
+	}
+
+	// lines between and after the hunks, and removed ones, have no position.
+	for _, line := range []int{0, 6, 11, 15} {
+		if _, err := diffPosition(testFile(testPatch), line); err == nil {
+			t.Errorf("line %d: got no error", line)
+		}
+	}
+
+	if _, err := diffPosition(testFile(""), 1); err == nil {
+		t.Error("got no error for a file without a patch")
+	}
+}
+
+func TestCheckLineRange(t *testing.T) {
+	for _, test := range []struct {
+		side       string
+		start, end int
+		ok         bool
+	}{
+		{"RIGHT", 2, 4, true},
+		{"RIGHT", 1, 5, true},
+		{"RIGHT", 12, 14, true},
+		{"RIGHT", 5, 12, false},
+		{"RIGHT", 6, 6, false},
+		{"RIGHT", 14, 15, false},
+		{"LEFT", 1, 3, true},
+		{"LEFT", 10, 11, true},
+		{"LEFT", 3, 10, false},
+		{"LEFT", 12, 12, false},
+		{"LEFT", 4, 4, false},
+	} {
+		err := checkLineRange(testFile(testPatch), test.side, test.start, test.end)
+		if (err == nil) != test.ok {
+			t.Errorf("%s %d-%d: got error %v, want ok %v", test.side, test.start, test.end, err, test.ok)
+		}
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	for _, test := range []struct {
+		arg        string
+		path       string
+		start, end int
+	}{
+		{"main.go:3", "main.go", 3, 3},
+		{"cmd/barb/main.go:3-7", "cmd/barb/main.go", 3, 7},
+		{"odd:name.go:4", "odd:name.go", 4, 4},
+		{"main.go:5-5", "main.go", 5, 5},
+	} {
+		path, start, end, err := parseLocation(test.arg)
+		if err != nil || path != test.path || start != test.start || end != test.end {
+			t.Errorf("%q: got %q %d-%d and error %v, want %q %d-%d", test.arg, path, start, end, err, test.path, test.start, test.end)
+		}
+	}
+
+	for _, arg := range []string{"main.go", ":3", "main.go:", "main.go:0", "main.go:x", "main.go:5-3", "main.go:3-", "main.go:3-x"} {
+		if _, _, _, err := parseLocation(arg); err == nil {
+			t.Errorf("%q: got no error", arg)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+// pullDrafts reports which of the numbered pull requests are drafts. The
+// REST API in go-github predates drafts, so this asks GraphQL, one aliased
+// field per pull request and a single request per hundred of them.
+func pullDrafts(client *github.Client, owner, repo string, nums []int) (map[int]bool, error) {
+	drafts := map[int]bool{}
+
+	for len(nums) > 0 {
+		batch := nums
+		if len(batch) > 100 {
+			batch = batch[:100]
+		}
+		nums = nums[len(batch):]
+
+		fields := []string{}
+		for _, num := range batch {
+			fields = append(fields, fmt.Sprintf("pr%d: pullRequest(number: %d) { isDraft }", num, num))
+		}
+
+		query := fmt.Sprintf("query($owner: String!, $repo: String!) { repository(owner: $owner, name: $repo) { %s } }", strings.Join(fields, " "))
+
+		result := struct {
+			Repository map[string]struct {
+				IsDraft bool `json:"isDraft"`
+			} `json:"repository"`
+		}{}
+
+		if err := newGraphQLClient(client).query(query, map[string]interface{}{"owner": owner, "repo": repo}, &result); err != nil {
+			return nil, err
+		}
+
+		for alias, pr := range result.Repository {
+			num, err := strconv.Atoi(strings.TrimPrefix(alias, "pr"))
+			if err == nil {
+				drafts[num] = pr.IsDraft
+			}
+		}
+	}
+
+	return drafts, nil
+}
+
+// isDraft is pullDrafts for a single pull request. Hosts without draft
+// support get a warning and are treated as having no drafts.
+func isDraft(client *github.Client, owner, repo string, num int) bool {
+	drafts, err := pullDrafts(client, owner, repo, []int{num})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: could not tell whether PR %d is a draft: %v\n", num, err)
+		return false
+	}
+
+	return drafts[num]
+}
+
+const (
+	markReadyMutation = `mutation($id: ID!) {
+  markPullRequestReadyForReview(input: {pullRequestId: $id}) { clientMutationId }
+}`
+
+	convertToDraftMutation = `mutation($id: ID!) {
+  convertPullRequestToDraft(input: {pullRequestId: $id}) { clientMutationId }
+}`
+)
+
+func readyPR(ctx *cli.Context) {
+	setDraft(ctx, false)
+}
+
+func draftPR(ctx *cli.Context) {
+	setDraft(ctx, true)
+}
+
+func setDraft(ctx *cli.Context, draft bool) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil {
+		exitError(err)
+	}
+
+	owner, repo, err := repo()
+	if err != nil {
+		exitError(err)
+	}
+
+	client := getClient()
+
+	pr, _, err := client.PullRequests.Get(context.Background(), owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	mutation, action := markReadyMutation, "marked ready for review"
+	if draft {
+		mutation, action = convertToDraftMutation, "converted to draft"
+	}
+
+	if err := newGraphQLClient(client).query(mutation, map[string]interface{}{"id": pr.GetNodeID()}, nil); err != nil {
+		exitError(err)
+	}
+
+	printResult(ctx, resultView{Number: num, Action: action, URL: pr.GetHTMLURL()}, fmt.Sprintf("PR %d %s!", num, action))
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+var currentLogin string
+
+// resolveLogin expands @me to the login of the authenticated user.
+func resolveLogin(client *github.Client, login string) (string, error) {
+	if login != "@me" {
+		return login, nil
+	}
+
+	if currentLogin == "" {
+		user, _, err := client.Users.Get(context.Background(), "")
+		if err != nil {
+			return "", fmt.Errorf("resolving @me: %v", err)
+		}
+
+		currentLogin = user.GetLogin()
+	}
+
+	return currentLogin, nil
+}
+
+// parseSince accepts an RFC3339 timestamp, a YYYY-MM-DD date, or a duration
+// before now such as 36h or 7d.
+func parseSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return t, nil
+	}
+
+	if strings.HasSuffix(since, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(since, "d")); err == nil {
+			return time.Now().AddDate(0, 0, -days), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --since %q; use a date, an RFC3339 time or a duration like 7d", since)
+}
+
+// milestoneNumber resolves a milestone given by number or title to the
+// number the issues API filters on. "*" and "none" pass through.
+func milestoneNumber(client *github.Client, owner, repo, milestone string) (string, error) {
+	if milestone == "" || milestone == "*" || milestone == "none" {
+		return milestone, nil
+	}
+
+	if _, err := strconv.Atoi(milestone); err == nil {
+		return milestone, nil
+	}
+
+	m, err := findMilestone(client, owner, repo, milestone)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(m.GetNumber()), nil
+}
+
+func findMilestone(client *github.Client, owner, repo, milestone string) (*github.Milestone, error) {
+	if num, err := strconv.Atoi(milestone); err == nil {
+		m, _, err := client.Issues.GetMilestone(context.Background(), owner, repo, num)
+		return m, err
+	}
+
+	var found *github.Milestone
+
+	// a limit of one stops paging at the first match.
+	p := &pager{limit: 1}
+	err := p.each(func(opts github.ListOptions) (*github.Response, error) {
+		milestones, resp, err := client.Issues.ListMilestones(context.Background(), owner, repo, &github.MilestoneListOptions{
+			State:       "all",
+			ListOptions: opts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range milestones {
+			if strings.EqualFold(m.GetTitle(), milestone) && p.take() {
+				found = m
+				break
+			}
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no milestone named %q", milestone)
+	}
+
+	return found, nil
+}
+
+func searchQuote(value string) string {
+	if strings.ContainsAny(value, " \t\"") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+// prSearchQuery builds a Search API query for the pull request filters the
+// list endpoint cannot apply itself. It returns "" when no such filter is set.
+func prSearchQuery(client *github.Client, ctx *cli.Context, owner, repo string) (string, error) {
+	terms := []string{}
+
+	for _, qualifier := range []string{"author", "assignee", "mentions"} {
+		if value := ctx.String(qualifier); value != "" {
+			login, err := resolveLogin(client, value)
+			if err != nil {
+				return "", err
+			}
+
+			terms = append(terms, qualifier+":"+login)
+		}
+	}
+
+	for _, label := range ctx.StringSlice("label") {
+		terms = append(terms, "label:"+searchQuote(label))
+	}
+
+	if milestone := ctx.String("milestone"); milestone != "" {
+		title := milestone
+		if _, err := strconv.Atoi(milestone); err == nil {
+			m, err := findMilestone(client, owner, repo, milestone)
+			if err != nil {
+				return "", err
+			}
+
+			title = m.GetTitle()
+		}
+
+		terms = append(terms, "milestone:"+searchQuote(title))
+	}
+
+	if ctx.IsSet("draft") {
+		terms = append(terms, fmt.Sprintf("draft:%v", ctx.Bool("draft")))
+	}
+
+	since, err := parseSince(ctx.String("since"))
+	if err != nil {
+		return "", err
+	}
+
+	if !since.IsZero() {
+		terms = append(terms, "updated:>="+since.Format(time.RFC3339))
+	}
+
+	// the list endpoint has no notion of merged, so that needs search too.
+	if len(terms) == 0 && ctx.String("state") != "merged" {
+		return "", nil
+	}
+
+	if base := ctx.String("base"); base != "" {
+		terms = append(terms, "base:"+base)
+	}
+
+	if head := ctx.String("head"); head != "" {
+		// search matches the branch name only, not owner:branch.
+		terms = append(terms, "head:"+head[strings.Index(head, ":")+1:])
+	}
+
+	switch state := ctx.String("state"); state {
+	case "open", "closed", "merged":
+		terms = append(terms, "is:"+state)
+	case "all":
+	default:
+		return "", fmt.Errorf("invalid state %q", state)
+	}
+
+	terms = append([]string{"repo:" + owner + "/" + repo, "is:pr"}, terms...)
+	return strings.Join(terms, " "), nil
+}
+
+// searchPRs passes the pull requests matching query to each, a page at a
+// time. Search results are issues, so only the issue fields are filled in;
+// fetchStatuses loads the rest.
+func searchPRs(client *github.Client, ctx *cli.Context, query string, p *pager, each func([]*github.PullRequest) error) error {
+	sort := ctx.String("sort-by")
+	if sort != "created" && sort != "updated" && sort != "comments" {
+		sort = ""
+	}
+
+	return p.each(func(opts github.ListOptions) (*github.Response, error) {
+		result, resp, err := client.Search.Issues(context.Background(), query, &github.SearchOptions{
+			Sort:        sort,
+			Order:       ctx.String("direction"),
+			ListOptions: opts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		pulls := []*github.PullRequest{}
+		for _, issue := range result.Issues {
+			if !p.take() {
+				break
+			}
+
+			pulls = append(pulls, &github.PullRequest{
+				Number:    issue.Number,
+				Title:     issue.Title,
+				State:     issue.State,
+				Body:      issue.Body,
+				User:      issue.User,
+				HTMLURL:   issue.HTMLURL,
+				CreatedAt: issue.CreatedAt,
+				UpdatedAt: issue.UpdatedAt,
+			})
+		}
+
+		return resp, each(pulls)
+	})
+}
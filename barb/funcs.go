@@ -7,70 +7,13 @@ import (
 	"io/ioutil"
 	"os"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/google/go-github/github"
 	"github.com/urfave/cli"
 )
 
-func watch(ctx *cli.Context) {
-	client := getClient()
-
-	args := ctx.Args()
-	if len(args) < 1 {
-		exitError(errors.New("invalid arguments"))
-	}
-
-	owner, repo, err := repo()
-	if err != nil {
-		exitError(err)
-	}
-
-	color.New(color.FgHiWhite).Printf("Monitoring PRs for %s/%s (ids: %v); will output as things finish.\n", owner, repo, args)
-
-	doneChan := make(chan []string, len(args))
-
-	for _, arg := range args {
-		go func(arg string) {
-			for {
-				num, err := strconv.Atoi(arg)
-				if err != nil {
-					exitError(err)
-				}
-
-				pr, _, err := client.PullRequests.Get(context.Background(), owner, repo, num)
-				if err != nil {
-					exitError(err)
-				}
-
-				status, _, err := client.Repositories.GetCombinedStatus(context.Background(), owner, repo, pr.Head.GetSHA(), nil)
-				if err != nil {
-					exitError(err)
-				}
-
-				if status.GetState() != "pending" {
-					doneChan <- []string{arg, status.GetState()}
-				}
-
-				time.Sleep(30 * time.Second)
-			}
-		}(arg)
-	}
-
-	var i int
-
-	for params := range doneChan {
-		i++
-		fmt.Printf("Finished: %v (%v)\n", params[0], params[1])
-		fmt.Printf("Remaining: %d\n", i-len(args))
-
-		if i == len(args) {
-			return
-		}
-	}
-}
-
 func reply(ctx *cli.Context) {
 	client := getClient()
 	if len(ctx.Args()) != 1 {
@@ -108,12 +51,12 @@ func reply(ctx *cli.Context) {
 		exitError(err)
 	}
 
-	_, _, err = client.PullRequests.CreateComment(context.Background(), owner, repo, num, &github.PullRequestComment{Body: github.String(string(content))})
+	comment, _, err := client.PullRequests.CreateComment(context.Background(), owner, repo, num, &github.PullRequestComment{Body: github.String(string(content))})
 	if err != nil {
 		exitError(err)
 	}
 
-	fmt.Printf("Comment on ticket %s posted!\n", args[0])
+	printResult(ctx, resultView{Number: num, Action: "commented", URL: comment.GetHTMLURL()}, fmt.Sprintf("Comment on ticket %s posted!", args[0]))
 }
 
 func get(ctx *cli.Context) {
@@ -141,20 +84,58 @@ func get(ctx *cli.Context) {
 	}
 
 	allComments := []*github.PullRequestComment{}
-	comments := []*github.PullRequestComment{}
-
-	for i := 0; len(comments) != 0; i++ {
-		comments, _, err = client.PullRequests.ListComments(context.Background(), owner, repo, num, &github.PullRequestListCommentsOptions{
-			ListOptions: github.ListOptions{
-				Page:    i,
-				PerPage: 100,
-			},
+
+	err = eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		comments, resp, err := client.PullRequests.ListComments(context.Background(), owner, repo, num, &github.PullRequestListCommentsOptions{
+			ListOptions: opts,
 		})
 		if err != nil {
-			exitError(err)
+			return nil, err
 		}
 
 		allComments = append(allComments, comments...)
+		return resp, nil
+	})
+	if err != nil {
+		exitError(err)
+	}
+
+	timeline, err := pullTimeline(client, owner, repo, num, allComments)
+	if err != nil {
+		exitError(err)
+	}
+
+	// the checks, draft flag and review decision come in a single query.
+	var checks []checkView
+	draft, review := false, ""
+
+	summary, err := newGraphQLClient(client).pullRequest(owner, repo, num)
+	if err == nil {
+		checks = summary.checks()
+		draft, review = summary.IsDraft, summary.review()
+	} else {
+		fmt.Fprintf(os.Stderr, "warning: %v; falling back to the REST API\n", err)
+
+		checks, err = headChecks(context.Background(), client, owner, repo, pr.Head.GetSHA())
+		if err != nil {
+			exitError(err)
+		}
+	}
+
+	if structuredOutput(ctx) {
+		view := newPullRequestView(pr)
+		view.Draft = draft
+		view.Status = newStatusView(checks)
+		view.Review = review
+
+		for _, comment := range allComments {
+			view.Comments = append(view.Comments, newCommentView(comment.User, comment.GetBody(), comment.CreatedAt, comment.GetHTMLURL()))
+		}
+
+		view.Timeline = timeline
+
+		printStructured(ctx, view)
+		return
 	}
 
 	color.Output = os.Stdout
@@ -174,14 +155,36 @@ func get(ctx *cli.Context) {
 		stateColor = color.New(color.FgRed)
 	}
 
-	stateColor.Printf("State: %s\n", pr.GetState())
+	if draft {
+		stateColor.Printf("State: %s (draft)\n", pr.GetState())
+	} else {
+		stateColor.Printf("State: %s\n", pr.GetState())
+	}
 
-	status, _, err := client.Repositories.GetCombinedStatus(context.Background(), owner, repo, pr.Head.GetSHA(), nil)
-	if err != nil {
-		exitError(err)
+	if len(pr.Labels) > 0 {
+		labels := []string{}
+		for _, label := range pr.Labels {
+			labels = append(labels, label.GetName())
+		}
+
+		color.New(color.FgMagenta).Printf("Labels: %s\n", strings.Join(labels, ", "))
 	}
 
-	switch status.GetState() {
+	if review != "" {
+		reviewColor := color.New(color.FgWhite)
+		switch review {
+		case "approved":
+			reviewColor = color.New(color.FgGreen)
+		case "changes requested":
+			reviewColor = color.New(color.FgRed)
+		}
+
+		reviewColor.Printf("Review: %s\n", review)
+	}
+
+	state := overallState(checks)
+
+	switch state {
 	case "success":
 		stateColor = color.New(color.FgHiGreen)
 	case "pending":
@@ -194,30 +197,22 @@ func get(ctx *cli.Context) {
 
 	stateColor.Print("Hooks State: ")
 
-	if status.GetState() == "success" {
-		stateColor.Println("success")
+	if state == "success" || state == "none" {
+		stateColor.Println(state)
 	} else {
 		stateColor.Println()
 
-		for _, state := range status.Statuses {
-			if state.GetState() != "success" {
-				stateColor.Println("\t", state.GetContext(), ":", state.GetTargetURL())
+		for _, check := range checks {
+			if stateClass(check.State) != "success" {
+				stateColor.Println("\t", check.Name, ":", check.URL)
 			}
 		}
 	}
 
 	line()
-	fmt.Println(pr.GetBody())
+	printBody(pr.GetBody(), "", ctx.Bool("raw"))
 
-	for _, comment := range comments {
-		fmt.Println()
-		line()
-		color.New(color.FgWhite).Printf("From: %s\n", comment.User.GetLogin())
-		color.New(color.FgWhite).Printf("Date: %s\n", comment.CreatedAt.Local())
-		line()
-		fmt.Println()
-		fmt.Println(comment.GetBody())
-	}
+	printTimeline(timeline, ctx.Bool("raw"))
 
 	fmt.Println()
 }
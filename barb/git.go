@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+type commit struct {
+	Subject string
+	Body    string
+}
+
+func currentBranch() (string, error) {
+	branch, err := gitOutput("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil || branch == "" {
+		return "", errors.New("not on a branch; name the head branch explicitly")
+	}
+
+	return branch, nil
+}
+
+// pushRemote is the remote branch would be pushed to: its configured
+// pushRemote or remote, remote.pushDefault, or remoteName().
+func pushRemote(branch string) string {
+	for _, key := range []string{"branch." + branch + ".pushRemote", "remote.pushDefault", "branch." + branch + ".remote"} {
+		if remote := gitConfig(key); remote != "" && remote != "." {
+			return remote
+		}
+	}
+
+	return remoteName()
+}
+
+// commitsSince lists the commits on branch that are not on base, oldest
+// first. base is fetched from remote when there is no remote-tracking branch
+// yet.
+func commitsSince(remote, base, branch string) ([]commit, error) {
+	ref := "refs/remotes/" + remote + "/" + base
+	if _, err := gitOutput("rev-parse", "--verify", "--quiet", ref); err != nil {
+		if err := runGit("fetch", remote, base); err != nil {
+			return nil, err
+		}
+
+		ref = "FETCH_HEAD"
+	}
+
+	mergeBase, err := gitOutput("merge-base", ref, "refs/heads/"+branch)
+	if err != nil {
+		return nil, err
+	}
+
+	// fields are split by NUL and records by the ASCII record separator, which
+	// cannot appear in a commit message that git would accept from a human.
+	out, err := gitOutput("log", "--reverse", "--format=%s%x00%b%x1e", mergeBase+"..refs/heads/"+branch)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []commit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+
+		parts := strings.SplitN(record, "\x00", 2)
+		c := commit{Subject: parts[0]}
+		if len(parts) == 2 {
+			c.Body = strings.TrimSpace(parts[1])
+		}
+
+		commits = append(commits, c)
+	}
+
+	return commits, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// graphQLPageSize is how many nodes barb asks for per page of a connection.
+// Pull requests carry their checks, so pages are kept well under the limit.
+const graphQLPageSize = 50
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// graphQLClient talks to the v4 API of the host a REST client points at.
+// It goes through the same client, so authentication, retries and the proxy
+// apply.
+type graphQLClient struct {
+	client *github.Client
+}
+
+func newGraphQLClient(client *github.Client) *graphQLClient {
+	return &graphQLClient{client: client}
+}
+
+// graphQLURL is the GraphQL endpoint next to client's REST API:
+// api.github.com/graphql, or /api/graphql for Enterprise's /api/v3/.
+func graphQLURL(client *github.Client) string {
+	u := *client.BaseURL
+
+	if strings.HasSuffix(u.Path, "/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/graphql"
+	}
+
+	return u.String()
+}
+
+// query runs query and decodes its data into v.
+func (gc *graphQLClient) query(query string, variables map[string]interface{}, v interface{}) error {
+	req, err := gc.client.NewRequest("POST", graphQLURL(gc.client), map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}{}
+
+	if _, err := gc.client.Do(context.Background(), req, &result); err != nil {
+		return err
+	}
+
+	if len(result.Errors) > 0 {
+		messages := []string{}
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+
+		return errors.New("graphql: " + strings.Join(messages, "; "))
+	}
+
+	if v == nil || len(result.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(result.Data, v)
+}
+
+// paginate runs query once per page of a connection, passing the previous
+// page's end cursor as $after, until p says to stop. page decodes the data
+// of each response and returns the connection's pageInfo.
+func (gc *graphQLClient) paginate(query string, variables map[string]interface{}, p *pager, page func(data json.RawMessage) (*pageInfo, error)) error {
+	vars := map[string]interface{}{}
+	for k, v := range variables {
+		vars[k] = v
+	}
+
+	var after *string
+
+	for fetched := 1; ; fetched++ {
+		vars["after"] = after
+
+		var data json.RawMessage
+		if err := gc.query(query, vars, &data); err != nil {
+			return err
+		}
+
+		info, err := page(data)
+		if err != nil {
+			return err
+		}
+
+		if !p.more(fetched, info.HasNextPage) {
+			return nil
+		}
+
+		cursor := info.EndCursor
+		after = &cursor
+	}
+}
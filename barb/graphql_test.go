@@ -0,0 +1,481 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+// fakeGitHub serves the connection in nodes to the pull request, search and
+// issue queries, a page of first nodes at a time, and a fixed pull request,
+// issue and commit status for the REST fallback. GraphQL requests from
+// failFrom on get an error, as a host missing part of the schema would send.
+type fakeGitHub struct {
+	mutex     sync.Mutex
+	nodes     []map[string]interface{}
+	failFrom  int
+	variables []map[string]interface{}
+	rest      []string
+}
+
+func newFakeGitHub(t *testing.T, nodes []map[string]interface{}) (*fakeGitHub, *github.Client) {
+	fg := &fakeGitHub{nodes: nodes, failFrom: -1}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", fg.graphQL)
+	mux.HandleFunc("/repos/o/r/", fg.restAPI)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return fg, client
+}
+
+func (fg *fakeGitHub) graphQL(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	fg.variables = append(fg.variables, req.Variables)
+
+	if fg.failFrom >= 0 && len(fg.variables) > fg.failFrom {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"message": "Field 'statusCheckRollup' doesn't exist on type 'Commit'"}},
+		})
+		return
+	}
+
+	start := 0
+	if after, ok := req.Variables["after"].(string); ok {
+		start, _ = strconv.Atoi(strings.TrimPrefix(after, "c"))
+	}
+
+	// queries other than the lists, such as the draft lookup, have no first.
+	first, _ := req.Variables["first"].(float64)
+
+	end := start + int(first)
+	if end > len(fg.nodes) {
+		end = len(fg.nodes)
+	}
+
+	connection := map[string]interface{}{
+		"pageInfo": pageInfo{HasNextPage: end < len(fg.nodes), EndCursor: fmt.Sprintf("c%d", end)},
+		"nodes":    fg.nodes[start:end],
+	}
+
+	var data interface{}
+	switch {
+	case strings.Contains(req.Query, "search("):
+		data = map[string]interface{}{"search": connection}
+	case strings.Contains(req.Query, "pullRequests("):
+		data = map[string]interface{}{"repository": map[string]interface{}{"pullRequests": connection}}
+	case strings.Contains(req.Query, "issues("):
+		data = map[string]interface{}{"repository": map[string]interface{}{"issues": connection}}
+	default:
+		data = map[string]interface{}{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func (fg *fakeGitHub) restAPI(w http.ResponseWriter, r *http.Request) {
+	fg.mutex.Lock()
+	fg.rest = append(fg.rest, r.URL.Path)
+	fg.mutex.Unlock()
+
+	switch r.URL.Path {
+	case "/repos/o/r/pulls":
+		fmt.Fprint(w, `[{"number": 7, "title": "rest pull", "state": "open", "user": {"login": "alice"}, "head": {"ref": "topic", "sha": "abc"}, "base": {"ref": "master"}}]`)
+	case "/repos/o/r/issues":
+		fmt.Fprint(w, `[{"number": 8, "title": "rest issue", "state": "open", "user": {"login": "bob"}}, {"number": 7, "title": "rest pull", "pull_request": {"url": "x"}}]`)
+	case "/repos/o/r/commits/abc/status":
+		fmt.Fprint(w, `{"state": "success", "statuses": [{"context": "ci", "state": "success"}]}`)
+	case "/repos/o/r/commits/abc/check-runs":
+		fmt.Fprint(w, `{"total_count": 0, "check_runs": []}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (fg *fakeGitHub) requests() ([]map[string]interface{}, []string) {
+	fg.mutex.Lock()
+	defer fg.mutex.Unlock()
+
+	return fg.variables, fg.rest
+}
+
+func pullNode(num int, headOwner string) map[string]interface{} {
+	return map[string]interface{}{
+		"number":              num,
+		"title":               fmt.Sprintf("pull %d", num),
+		"state":               "OPEN",
+		"author":              map[string]string{"login": "alice"},
+		"headRefName":         "topic",
+		"headRepositoryOwner": map[string]string{"login": headOwner},
+		"labels":              map[string]interface{}{"nodes": []map[string]string{{"name": "bug"}}},
+		"comments":            map[string]int{"totalCount": 2},
+		"commits": map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"commit": map[string]interface{}{
+			"statusCheckRollup": map[string]interface{}{
+				"state": "PENDING",
+				"contexts": map[string]interface{}{"nodes": []map[string]string{
+					{"__typename": "CheckRun", "name": "build", "status": "COMPLETED", "conclusion": "SUCCESS"},
+					{"__typename": "CheckRun", "name": "lint", "status": "IN_PROGRESS"},
+					{"__typename": "StatusContext", "context": "deploy", "state": "EXPECTED"},
+				}},
+			},
+		}}}},
+	}
+}
+
+func pullNodes(count int) []map[string]interface{} {
+	nodes := []map[string]interface{}{}
+	for i := 1; i <= count; i++ {
+		nodes = append(nodes, pullNode(i, "o"))
+	}
+
+	return nodes
+}
+
+// listContext is a context carrying the flags of pr list and issue list.
+func listContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("list", flag.ContinueOnError)
+	set.String("state", "open", "")
+	set.String("sort-by", "created", "")
+	set.String("direction", "desc", "")
+	set.String("base", "", "")
+	set.String("head", "", "")
+	set.Int("limit", 0, "")
+	set.Int("max-pages", 0, "")
+	set.Bool("all", false, "")
+	set.Bool("reviews", false, "")
+	set.Int("concurrency", 2, "")
+
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestGraphQLURL(t *testing.T) {
+	for base, want := range map[string]string{
+		"https://api.github.com/":            "https://api.github.com/graphql",
+		"https://ghe.example.com/api/v3/":    "https://ghe.example.com/api/graphql",
+		"https://ghe.example.com/custom/v3/": "https://ghe.example.com/custom/graphql",
+	} {
+		client := github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(base)
+
+		if got := graphQLURL(client); got != want {
+			t.Errorf("graphQLURL(%s) = %s, want %s", base, got, want)
+		}
+	}
+}
+
+func TestPaginateCursors(t *testing.T) {
+	fg, client := newFakeGitHub(t, pullNodes(120))
+
+	nums := []int{}
+	err := newGraphQLClient(client).pullRequests("o", "r", pullFilter{}, &pager{}, func(pull *pullSummary) {
+		nums = append(nums, pull.Number)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nums) != 120 || nums[0] != 1 || nums[119] != 120 {
+		t.Fatalf("got %d pull requests from %v to %v, want 1 to 120", len(nums), nums[0], nums[len(nums)-1])
+	}
+
+	variables, _ := fg.requests()
+
+	afters := []interface{}{}
+	for _, vars := range variables {
+		afters = append(afters, vars["after"])
+	}
+
+	if want := []interface{}{nil, "c50", "c100"}; !reflect.DeepEqual(afters, want) {
+		t.Fatalf("got cursors %v, want %v", afters, want)
+	}
+}
+
+func TestPaginateStops(t *testing.T) {
+	for _, test := range []struct {
+		pager    pager
+		pulls    int
+		requests int
+		first    float64
+	}{
+		{pager{limit: 3}, 3, 1, 3},
+		{pager{limit: 70}, 70, 2, 50},
+		{pager{pages: 1}, 50, 1, 50},
+		{pager{pages: 2}, 100, 2, 50},
+	} {
+		fg, client := newFakeGitHub(t, pullNodes(120))
+
+		p := test.pager
+		pulls := 0
+		if err := newGraphQLClient(client).pullRequests("o", "r", pullFilter{}, &p, func(*pullSummary) { pulls++ }); err != nil {
+			t.Fatal(err)
+		}
+
+		variables, _ := fg.requests()
+		if pulls != test.pulls || len(variables) != test.requests || variables[0]["first"] != test.first {
+			t.Errorf("%+v: got %d pulls in %d requests of %v, want %d in %d of %v", test.pager, pulls, len(variables), variables[0]["first"], test.pulls, test.requests, test.first)
+		}
+	}
+}
+
+func TestPullRequestsQuery(t *testing.T) {
+	merged := pullNode(3, "o")
+	merged["state"] = "MERGED"
+	merged["isDraft"] = true
+	fg, client := newFakeGitHub(t, []map[string]interface{}{pullNode(1, "o"), pullNode(2, "fork"), merged})
+
+	views := []pullRequestView{}
+	filter := pullFilter{
+		states:    []string{"OPEN", "MERGED"},
+		base:      "master",
+		head:      "topic",
+		headOwner: "o",
+		orderBy:   "UPDATED_AT",
+		direction: "ASC",
+	}
+
+	err := newGraphQLClient(client).pullRequests("o", "r", filter, &pager{}, func(pull *pullSummary) {
+		views = append(views, pull.view())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variables, _ := fg.requests()
+	vars := variables[0]
+
+	if vars["owner"] != "o" || vars["repo"] != "r" || vars["base"] != "master" || vars["head"] != "topic" {
+		t.Errorf("got variables %v", vars)
+	}
+
+	if !reflect.DeepEqual(vars["states"], []interface{}{"OPEN", "MERGED"}) {
+		t.Errorf("got states %v", vars["states"])
+	}
+
+	if !reflect.DeepEqual(vars["orderBy"], map[string]interface{}{"field": "UPDATED_AT", "direction": "ASC"}) {
+		t.Errorf("got orderBy %v", vars["orderBy"])
+	}
+
+	// pull 2 comes from a fork with a branch of the same name.
+	if len(views) != 2 || views[0].Number != 1 || views[1].Number != 3 {
+		t.Fatalf("got %+v, want pulls 1 and 3", views)
+	}
+
+	if views[1].State != "closed" || !views[1].Draft {
+		t.Errorf("got state %q and draft %v for a merged draft, want closed and true", views[1].State, views[1].Draft)
+	}
+
+	view := views[0]
+	if view.Author != "alice" || !reflect.DeepEqual(view.Labels, []string{"bug"}) || view.CommentCount != 2 || view.Review != "none" {
+		t.Errorf("got %+v", view)
+	}
+
+	if view.Status.State != "pending" {
+		t.Errorf("got status %+v, want pending", view.Status)
+	}
+
+	states := []string{}
+	for _, context := range view.Status.Contexts {
+		states = append(states, context.Context+":"+context.State)
+	}
+
+	if want := []string{"build:success", "lint:in_progress", "deploy:pending"}; !reflect.DeepEqual(states, want) {
+		t.Errorf("got checks %v, want %v", states, want)
+	}
+}
+
+func TestSearchPullRequests(t *testing.T) {
+	fg, client := newFakeGitHub(t, pullNodes(60))
+
+	ctx := listContext(t, "--sort-by", "updated", "--direction", "asc", "--limit", "55")
+
+	nums := []int{}
+	err := queryPRs(client, ctx, "o", "r", "repo:o/r is:pr author:alice", newPager(ctx), func(view pullRequestView) {
+		nums = append(nums, view.Number)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nums) != 55 {
+		t.Fatalf("got %d pull requests, want the limit of 55", len(nums))
+	}
+
+	variables, _ := fg.requests()
+	if len(variables) != 2 || variables[1]["after"] != "c50" {
+		t.Fatalf("got requests %v, want a second page after c50", variables)
+	}
+
+	if want := "repo:o/r is:pr author:alice sort:updated-asc"; variables[0]["query"] != want {
+		t.Errorf("got query %q, want %q", variables[0]["query"], want)
+	}
+}
+
+func TestIssuesQuery(t *testing.T) {
+	fg, client := newFakeGitHub(t, []map[string]interface{}{{
+		"number":    4,
+		"title":     "broken",
+		"state":     "CLOSED",
+		"author":    map[string]string{"login": "bob"},
+		"labels":    map[string]interface{}{"nodes": []map[string]string{{"name": "bug"}}},
+		"assignees": map[string]interface{}{"nodes": []map[string]string{{"login": "carol"}}},
+		"milestone": map[string]string{"title": "v1"},
+		"comments":  map[string]int{"totalCount": 5},
+	}})
+
+	filter := issueFilter{
+		states:    []string{"CLOSED"},
+		labels:    []string{"bug"},
+		author:    "bob",
+		milestone: "2",
+		orderBy:   "COMMENTS",
+		direction: "DESC",
+	}
+
+	views := []issueView{}
+	err := newGraphQLClient(client).issues("o", "r", filter, &pager{}, func(issue *issueSummary) {
+		views = append(views, issue.view())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variables, _ := fg.requests()
+	vars := variables[0]
+
+	if want := map[string]interface{}{"createdBy": "bob", "milestoneNumber": "2"}; !reflect.DeepEqual(vars["filterBy"], want) {
+		t.Errorf("got filterBy %v, want %v", vars["filterBy"], want)
+	}
+
+	if !reflect.DeepEqual(vars["labels"], []interface{}{"bug"}) || !reflect.DeepEqual(vars["states"], []interface{}{"CLOSED"}) {
+		t.Errorf("got variables %v", vars)
+	}
+
+	want := issueView{
+		Number:       4,
+		Title:        "broken",
+		State:        "closed",
+		Author:       "bob",
+		Labels:       []string{"bug"},
+		Assignees:    []string{"carol"},
+		Milestone:    "v1",
+		CommentCount: 5,
+	}
+
+	if len(views) != 1 || !reflect.DeepEqual(views[0], want) {
+		t.Fatalf("got %+v, want %+v", views, want)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	fg, client := newFakeGitHub(t, pullNodes(1))
+	fg.failFrom = 0
+
+	err := newGraphQLClient(client).pullRequests("o", "r", pullFilter{}, &pager{}, func(*pullSummary) {})
+	if err == nil || !strings.HasPrefix(err.Error(), "graphql: Field 'statusCheckRollup'") {
+		t.Fatalf("got error %v, want the GraphQL error", err)
+	}
+}
+
+func TestPRListFallback(t *testing.T) {
+	fg, client := newFakeGitHub(t, pullNodes(1))
+	fg.failFrom = 0
+
+	views := []pullRequestView{}
+	if err := eachPR(client, listContext(t), "o", "r", "", func(view pullRequestView) { views = append(views, view) }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(views) != 1 || views[0].Number != 7 || views[0].Title != "rest pull" {
+		t.Fatalf("got %+v, want the pull request from REST", views)
+	}
+
+	if views[0].Status.State != "success" || len(views[0].Status.Contexts) != 1 {
+		t.Errorf("got status %+v, want the REST commit status", views[0].Status)
+	}
+
+	_, rest := fg.requests()
+	if rest[0] != "/repos/o/r/pulls" {
+		t.Errorf("got REST requests %v", rest)
+	}
+}
+
+func TestPRListRESTOnlySort(t *testing.T) {
+	fg, client := newFakeGitHub(t, pullNodes(1))
+
+	views := []pullRequestView{}
+	if err := eachPR(client, listContext(t, "--sort-by", "popularity"), "o", "r", "", func(view pullRequestView) { views = append(views, view) }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(views) != 1 || views[0].Number != 7 {
+		t.Fatalf("got %+v, want the pull request from REST", views)
+	}
+
+	// the only GraphQL request is the REST path asking which are drafts.
+	variables, _ := fg.requests()
+	if len(variables) != 1 || variables[0]["first"] != nil {
+		t.Errorf("got GraphQL requests %v, want only the draft lookup", variables)
+	}
+}
+
+func TestPRListNoFallbackAfterOutput(t *testing.T) {
+	fg, client := newFakeGitHub(t, pullNodes(60))
+	fg.failFrom = 1
+
+	shown := 0
+	err := eachPR(client, listContext(t, "--all"), "o", "r", "", func(pullRequestView) { shown++ })
+	if err == nil || shown != 50 {
+		t.Fatalf("got error %v after %d pull requests, want an error after the first page", err, shown)
+	}
+
+	if _, rest := fg.requests(); len(rest) != 0 {
+		t.Errorf("got REST requests %v, want none once output started", rest)
+	}
+}
+
+func TestIssueListFallback(t *testing.T) {
+	fg, client := newFakeGitHub(t, nil)
+	fg.failFrom = 0
+
+	views := []issueView{}
+	if err := eachIssue(client, listContext(t), "o", "r", issueFilter{}, func(view issueView) { views = append(views, view) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// the REST endpoint lists pull requests as well; they are left out.
+	if len(views) != 1 || views[0].Number != 8 || views[0].Author != "bob" {
+		t.Fatalf("got %+v, want only issue 8 from REST", views)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// hostConfig describes how to reach the GitHub API for a single host. The
+// host is taken from the repository's remote; its settings come from the
+// hosts section of the config file, or from git config under a subsection
+// named after it, e.g.:
+//
+//	[barb "github.example.com"]
+//	  apiurl = https://github.example.com/api/v3/
+//	  uploadurl = https://github.example.com/api/uploads/
+//	  token = ...
+//	  cafile = /etc/ssl/corp-ca.pem
+//	  proxy = http://proxy.example.com:3128
+type hostConfig struct {
+	Host      string
+	APIURL    string
+	UploadURL string
+	Token     string
+	CAFile    string
+	Proxy     string
+	ClientID  string
+
+	// GitHub App credentials; when AppID is set barb authenticates as the
+	// app's installation instead of with Token.
+	AppID          string
+	InstallationID string
+	PrivateKeyFile string
+
+	// TokenSource records where Token came from, for `barb auth status`.
+	TokenSource string
+}
+
+func lookupHost(host string) *hostConfig {
+	hs, ok := currentConfig.Hosts[host]
+	if !ok {
+		hs = &hostSettings{}
+	}
+
+	hc := &hostConfig{
+		Host:      host,
+		APIURL:    firstNonEmpty(hs.APIURL, gitConfig("barb."+host+".apiurl")),
+		UploadURL: firstNonEmpty(hs.UploadURL, gitConfig("barb."+host+".uploadurl")),
+		Token:     firstNonEmpty(hs.Token, gitConfig("barb."+host+".token")),
+		CAFile:    firstNonEmpty(hs.CAFile, gitConfig("barb."+host+".cafile")),
+		Proxy:     firstNonEmpty(hs.Proxy, gitConfig("barb."+host+".proxy")),
+		ClientID:  firstNonEmpty(hs.ClientID, gitConfig("barb."+host+".clientid"), os.Getenv("BARB_OAUTH_CLIENT_ID")),
+
+		AppID:          firstNonEmpty(hs.AppID, gitConfig("barb."+host+".appid")),
+		InstallationID: firstNonEmpty(hs.InstallationID, gitConfig("barb."+host+".installationid")),
+		PrivateKeyFile: firstNonEmpty(hs.PrivateKeyFile, gitConfig("barb."+host+".privatekeyfile")),
+	}
+
+	if hc.AppID != "" {
+		hc.TokenSource = "github app"
+	} else if hc.Token != "" {
+		hc.TokenSource = "config"
+	}
+
+	if hc.APIURL == "" {
+		if host == defaultHost {
+			hc.APIURL = "https://api.github.com/"
+		} else {
+			hc.APIURL = "https://" + host + "/api/v3/"
+		}
+	}
+
+	if hc.UploadURL == "" {
+		if host == defaultHost {
+			hc.UploadURL = "https://uploads.github.com/"
+		} else {
+			hc.UploadURL = "https://" + host + "/api/uploads/"
+		}
+	}
+
+	if hc.Token == "" && hc.AppID == "" {
+		token, source, err := storedToken(host)
+		if err == nil {
+			hc.Token, hc.TokenSource = token, source
+		} else if err != errTokenNotFound {
+			fmt.Fprintf(os.Stderr, "warning: could not read stored token for %s: %v\n", host, err)
+		}
+	}
+
+	// like the token variables below, these only stand in for a host without
+	// credentials of its own.
+	if hc.Token == "" && hc.AppID == "" && os.Getenv("BARB_APP_ID") != "" {
+		hc.AppID = os.Getenv("BARB_APP_ID")
+		hc.InstallationID = firstNonEmpty(hc.InstallationID, os.Getenv("BARB_APP_INSTALLATION_ID"))
+		hc.PrivateKeyFile = firstNonEmpty(hc.PrivateKeyFile, os.Getenv("BARB_APP_PRIVATE_KEY_FILE"))
+		hc.TokenSource = "github app"
+	}
+
+	if hc.Token == "" && hc.AppID == "" {
+		env := "GITHUB_TOKEN"
+		if host != defaultHost {
+			env = "GITHUB_ENTERPRISE_TOKEN"
+		}
+
+		if hc.Token = os.Getenv(env); hc.Token != "" {
+			hc.TokenSource = env
+		}
+	}
+
+	return hc
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func (hc *hostConfig) enterprise() bool {
+	return hc.Host != defaultHost
+}
+
+func (hc *hostConfig) githubClient(httpClient *http.Client) (*github.Client, error) {
+	if !hc.enterprise() {
+		return github.NewClient(httpClient), nil
+	}
+
+	return github.NewEnterpriseClient(hc.APIURL, hc.UploadURL, httpClient)
+}
+
+// httpClient talks to the host through the response cache.
+func (hc *hostConfig) httpClient() (*http.Client, error) {
+	transport, err := hc.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: newCacheTransport(transport)}, nil
+}
+
+// uncachedHTTPClient is httpClient without the cache, for responses that are
+// never asked for twice: job logs, and lookups signed with a fresh JWT.
+func (hc *hostConfig) uncachedHTTPClient() (*http.Client, error) {
+	transport, err := hc.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+func (hc *hostConfig) transport() (http.RoundTripper, error) {
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        100,
+	}
+
+	if hc.Proxy != "" {
+		proxy, err := url.Parse(hc.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q for %s: %v", hc.Proxy, hc.Host, err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if hc.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := ioutil.ReadFile(hc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle for %s: %v", hc.Host, err)
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", hc.CAFile)
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &retryTransport{base: transport}, nil
+}
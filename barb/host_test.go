@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestAppEnvironment(t *testing.T) {
+	// no git, keyring tool or stored tokens get in the way.
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("BARB_APP_ID", "2")
+	t.Setenv("BARB_APP_INSTALLATION_ID", "20")
+	t.Setenv("BARB_APP_PRIVATE_KEY_FILE", "/env.pem")
+	t.Setenv("GITHUB_TOKEN", "ghp_env")
+
+	saved := *currentConfig
+	t.Cleanup(func() { *currentConfig = saved })
+
+	currentConfig.Hosts = map[string]*hostSettings{
+		"app.example.com":   {AppID: "1", PrivateKeyFile: "/app.pem"},
+		"token.example.com": {Token: "ghp_config"},
+	}
+
+	for _, test := range []struct {
+		host   string
+		app    [3]string
+		token  string
+		source string
+	}{
+		{"app.example.com", [3]string{"1", "", "/app.pem"}, "", "github app"},
+		{"token.example.com", [3]string{"", "", ""}, "ghp_config", "config"},
+		{"github.com", [3]string{"2", "20", "/env.pem"}, "", "github app"},
+	} {
+		hc := lookupHost(test.host)
+
+		if app := [3]string{hc.AppID, hc.InstallationID, hc.PrivateKeyFile}; app != test.app || hc.Token != test.token || hc.TokenSource != test.source {
+			t.Errorf("%s: got app %v, token %q from %q, want app %v, token %q from %q", test.host, app, hc.Token, hc.TokenSource, test.app, test.token, test.source)
+		}
+	}
+}
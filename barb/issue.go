@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/google/go-github/github"
@@ -36,24 +37,31 @@ func getIssue(ctx *cli.Context) {
 	}
 
 	allComments := []*github.IssueComment{}
-	var i int
-
-	for {
-		i++
-		comments, _, err := client.Issues.ListComments(context.Background(), owner, repo, num, &github.IssueListCommentsOptions{
-			ListOptions: github.ListOptions{
-				Page: i,
-			},
+
+	err = eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		comments, resp, err := client.Issues.ListComments(context.Background(), owner, repo, num, &github.IssueListCommentsOptions{
+			ListOptions: opts,
 		})
 		if err != nil {
-			exitError(err)
+			return nil, err
 		}
 
-		if len(comments) == 0 {
-			break
+		allComments = append(allComments, comments...)
+		return resp, nil
+	})
+	if err != nil {
+		exitError(err)
+	}
+
+	if structuredOutput(ctx) {
+		view := newIssueView(issue)
+
+		for _, comment := range allComments {
+			view.Comments = append(view.Comments, newCommentView(comment.User, comment.GetBody(), comment.CreatedAt, comment.GetHTMLURL()))
 		}
 
-		allComments = append(allComments, comments...)
+		printStructured(ctx, view)
+		return
 	}
 
 	color.Output = os.Stdout
@@ -76,7 +84,7 @@ func getIssue(ctx *cli.Context) {
 	stateColor.Printf("State: %s\n", issue.GetState())
 
 	line()
-	fmt.Println(issue.GetBody())
+	printBody(issue.GetBody(), "", ctx.Bool("raw"))
 
 	for _, comment := range allComments {
 		fmt.Println()
@@ -85,7 +93,7 @@ func getIssue(ctx *cli.Context) {
 		color.New(color.FgWhite).Printf("Date: %s\n", comment.CreatedAt.Local())
 		line()
 		fmt.Println()
-		fmt.Println(comment.GetBody())
+		printBody(comment.GetBody(), "", ctx.Bool("raw"))
 	}
 
 	fmt.Println()
@@ -100,35 +108,130 @@ func listIssue(ctx *cli.Context) {
 		exitError(err)
 	}
 
-	newIssues := []*github.Issue{}
+	since, err := parseSince(ctx.String("since"))
+	if err != nil {
+		exitError(err)
+	}
 
-	for page := 1; page < ctx.Int("max-pages"); page++ {
-		params := &github.IssueListByRepoOptions{
-			State:     ctx.String("state"),
-			Sort:      ctx.String("sort-by"),
-			Direction: ctx.String("direction"),
-			ListOptions: github.ListOptions{
-				Page: page,
-			},
-		}
+	milestone, err := milestoneNumber(client, owner, repo, ctx.String("milestone"))
+	if err != nil {
+		exitError(err)
+	}
 
-		prs, _, err := client.Issues.ListByRepo(context.Background(), owner, repo, params)
+	logins := map[string]string{}
+	for _, name := range []string{"author", "assignee", "mentions"} {
+		logins[name], err = resolveLogin(client, ctx.String(name))
 		if err != nil {
 			exitError(err)
 		}
+	}
+
+	filter := issueFilter{
+		labels:    ctx.StringSlice("label"),
+		author:    logins["author"],
+		assignee:  logins["assignee"],
+		mentioned: logins["mentions"],
+		milestone: milestone,
+		since:     since,
+		direction: strings.ToUpper(ctx.String("direction")),
+	}
+
+	switch sort := ctx.String("sort-by"); sort {
+	case "created", "updated":
+		filter.orderBy = strings.ToUpper(sort) + "_AT"
+	case "comments":
+		filter.orderBy = "COMMENTS"
+	default:
+		exitError(fmt.Errorf("invalid sort %q", sort))
+	}
+
+	switch state := ctx.String("state"); state {
+	case "open", "closed":
+		filter.states = []string{strings.ToUpper(state)}
+	case "all":
+	default:
+		exitError(fmt.Errorf("invalid state %q", state))
+	}
+
+	structured := structuredOutput(ctx)
+
+	// text is printed as each page arrives; structured output is one document.
+	views := []issueView{}
+	show := func(issue issueView) {
+		if structured {
+			views = append(views, issue)
+			return
+		}
+
+		color.New(color.FgWhite).Printf("[ %d ] ", issue.Number)
+		color.New(color.FgBlue).Printf("(%s) ", issue.Author)
+		fmt.Fprintf(os.Stdout, "%s", issue.Title)
 
-		if len(prs) == 0 {
-			break
+		for _, label := range issue.Labels {
+			color.New(color.FgMagenta).Printf(" {%s}", label)
 		}
 
-		newIssues = append(newIssues, prs...)
+		fmt.Println()
+	}
+
+	if err := eachIssue(client, ctx, owner, repo, filter, show); err != nil {
+		exitError(err)
 	}
 
-	for _, issue := range newIssues {
-		color.New(color.FgWhite).Printf("[ %d ] ", issue.GetNumber())
-		color.New(color.FgBlue).Printf("(%s) ", issue.User.GetLogin())
-		fmt.Fprintf(os.Stdout, "%s\n", issue.GetTitle())
+	if structured {
+		printStructured(ctx, views)
+	}
+}
+
+// eachIssue passes the issues listIssue shows to show, through GraphQL
+// where it can and through the REST API otherwise.
+func eachIssue(client *github.Client, ctx *cli.Context, owner, repo string, filter issueFilter, show func(issueView)) error {
+	shown := 0
+	err := newGraphQLClient(client).issues(owner, repo, filter, newPager(ctx), func(issue *issueSummary) {
+		shown++
+		show(issue.view())
+	})
+
+	// older Enterprise releases lack parts of the schema, which shows on the
+	// first page; after that, starting over would repeat what was printed.
+	if err != nil && shown == 0 {
+		fmt.Fprintf(os.Stderr, "warning: %v; falling back to the REST API\n", err)
+
+		return listIssuesREST(client, ctx, owner, repo, filter, show)
 	}
+
+	return err
+}
+
+func listIssuesREST(client *github.Client, ctx *cli.Context, owner, repo string, filter issueFilter, show func(issueView)) error {
+	p := newPager(ctx)
+
+	return p.each(func(opts github.ListOptions) (*github.Response, error) {
+		issues, resp, err := client.Issues.ListByRepo(context.Background(), owner, repo, &github.IssueListByRepoOptions{
+			State:       ctx.String("state"),
+			Sort:        ctx.String("sort-by"),
+			Direction:   ctx.String("direction"),
+			Creator:     filter.author,
+			Assignee:    filter.assignee,
+			Mentioned:   filter.mentioned,
+			Labels:      filter.labels,
+			Milestone:   filter.milestone,
+			Since:       filter.since,
+			ListOptions: opts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			// the issues endpoint also returns pull requests.
+			if issue.PullRequestLinks == nil && p.take() {
+				show(newIssueView(issue))
+			}
+		}
+
+		return resp, nil
+	})
 }
 
 func replyIssue(ctx *cli.Context) {
@@ -166,23 +269,23 @@ func replyIssue(ctx *cli.Context) {
 		exitError(err)
 	}
 
-	_, _, err = client.Issues.CreateComment(context.Background(), owner, repo, num, &github.IssueComment{Body: github.String(string(body))})
+	comment, _, err := client.Issues.CreateComment(context.Background(), owner, repo, num, &github.IssueComment{Body: github.String(string(body))})
 	if err != nil {
 		exitError(err)
 	}
 
-	fmt.Println("Comment posted!")
+	printResult(ctx, resultView{Number: num, Action: "commented", URL: comment.GetHTMLURL()}, "Comment posted!")
 }
 
 func reopenIssue(ctx *cli.Context) {
-	editIssue(ctx, "opened")
+	setIssueState(ctx, "open", "reopened")
 }
 
 func closeIssue(ctx *cli.Context) {
-	editIssue(ctx, "closed")
+	setIssueState(ctx, "closed", "closed")
 }
 
-func editIssue(ctx *cli.Context, state string) {
+func setIssueState(ctx *cli.Context, state, action string) {
 	args := ctx.Args()
 
 	if len(args) != 1 {
@@ -209,5 +312,314 @@ func editIssue(ctx *cli.Context, state string) {
 		exitError(err)
 	}
 
-	fmt.Println("Issue", num, state+"!")
+	printResult(ctx, resultView{Number: num, Action: action}, fmt.Sprint("Issue ", num, " ", action, "!"))
+}
+
+func createIssue(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	client := getClient()
+
+	owner, repo, err := repo()
+	if err != nil {
+		exitError(err)
+	}
+
+	templates, err := issueTemplates()
+	if err != nil {
+		exitError(err)
+	}
+
+	var tmpl *issueTemplate
+
+	if name := ctx.String("template"); name != "" {
+		tmpl, err = findIssueTemplate(templates, name)
+	} else if ctx.String("body-file") == "" {
+		tmpl, err = chooseIssueTemplate(templates)
+	}
+
+	if err != nil {
+		exitError(err)
+	}
+
+	if tmpl == nil {
+		tmpl = &issueTemplate{}
+	}
+
+	title := ctx.String("title")
+	var body string
+
+	if file := ctx.String("body-file"); file != "" {
+		body, err = readBodyFile(file)
+		if err != nil {
+			exitError(err)
+		}
+
+		if title == "" {
+			exitError(errors.New("--title is required with --body-file"))
+		}
+	} else {
+		initial := tmpl.Body
+		if title == "" {
+			initial = tmpl.Title + "\n\n" + tmpl.Body
+		}
+
+		content, err := editText(initial)
+		if err != nil {
+			exitError(err)
+		}
+
+		if title == "" {
+			title, body = splitTitle(content)
+		} else {
+			body = strings.TrimSpace(content)
+		}
+	}
+
+	if strings.TrimSpace(title) == "" || strings.TrimSpace(title) == strings.TrimSpace(tmpl.Title) {
+		exitError(errors.New("issues must have a title"))
+	}
+
+	labels := append(append([]string{}, tmpl.Labels...), ctx.StringSlice("label")...)
+
+	assignees := []string{}
+	for _, assignee := range append(append([]string{}, tmpl.Assignees...), ctx.StringSlice("assignee")...) {
+		login, err := resolveLogin(client, assignee)
+		if err != nil {
+			exitError(err)
+		}
+
+		assignees = append(assignees, login)
+	}
+
+	req := &github.IssueRequest{
+		Title:     github.String(title),
+		Body:      github.String(body),
+		Labels:    &labels,
+		Assignees: &assignees,
+	}
+
+	if name := ctx.String("milestone"); name != "" {
+		m, err := findMilestone(client, owner, repo, name)
+		if err != nil {
+			exitError(err)
+		}
+
+		req.Milestone = m.Number
+	}
+
+	issue, _, err := client.Issues.Create(context.Background(), owner, repo, req)
+	if err != nil {
+		exitError(err)
+	}
+
+	printResult(ctx, resultView{Number: issue.GetNumber(), Action: "created", URL: issue.GetHTMLURL()}, fmt.Sprintf("Issue %d created: %s", issue.GetNumber(), issue.GetHTMLURL()))
+}
+
+type issueEditView struct {
+	Before issueView `json:"before" yaml:"before"`
+	After  issueView `json:"after" yaml:"after"`
+}
+
+func editIssue(ctx *cli.Context) {
+	args := ctx.Args()
+
+	if len(args) != 1 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	client := getClient()
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil {
+		exitError(err)
+	}
+
+	owner, repo, err := repo()
+	if err != nil {
+		exitError(err)
+	}
+
+	before, _, err := client.Issues.Get(context.Background(), owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	// sent as a raw PATCH because IssueRequest cannot express a null milestone.
+	patch := map[string]interface{}{}
+
+	if ctx.IsSet("title") {
+		patch["title"] = ctx.String("title")
+	}
+
+	if file := ctx.String("body-file"); file != "" {
+		body, err := readBodyFile(file)
+		if err != nil {
+			exitError(err)
+		}
+
+		patch["body"] = body
+	} else if ctx.Bool("body") {
+		body, err := editText(before.GetBody())
+		if err != nil {
+			exitError(err)
+		}
+
+		patch["body"] = body
+	}
+
+	if ctx.Bool("remove-milestone") {
+		patch["milestone"] = nil
+	} else if name := ctx.String("milestone"); name != "" {
+		m, err := findMilestone(client, owner, repo, name)
+		if err != nil {
+			exitError(err)
+		}
+
+		patch["milestone"] = m.GetNumber()
+	}
+
+	if len(patch) > 0 {
+		req, err := client.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/issues/%d", owner, repo, num), patch)
+		if err != nil {
+			exitError(err)
+		}
+
+		if _, err := client.Do(context.Background(), req, nil); err != nil {
+			exitError(err)
+		}
+	}
+
+	if labels := ctx.StringSlice("add-label"); len(labels) > 0 {
+		if _, _, err := client.Issues.AddLabelsToIssue(context.Background(), owner, repo, num, labels); err != nil {
+			exitError(err)
+		}
+	}
+
+	for _, label := range ctx.StringSlice("remove-label") {
+		if _, err := client.Issues.RemoveLabelForIssue(context.Background(), owner, repo, num, label); err != nil {
+			exitError(err)
+		}
+	}
+
+	for _, change := range []struct {
+		flag string
+		fn   func(context.Context, string, string, int, []string) (*github.Issue, *github.Response, error)
+	}{
+		{"add-assignee", client.Issues.AddAssignees},
+		{"remove-assignee", client.Issues.RemoveAssignees},
+	} {
+		logins := []string{}
+		for _, name := range ctx.StringSlice(change.flag) {
+			login, err := resolveLogin(client, name)
+			if err != nil {
+				exitError(err)
+			}
+
+			logins = append(logins, login)
+		}
+
+		if len(logins) == 0 {
+			continue
+		}
+
+		if _, _, err := change.fn(context.Background(), owner, repo, num, logins); err != nil {
+			exitError(err)
+		}
+	}
+
+	after, _, err := client.Issues.Get(context.Background(), owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	if structuredOutput(ctx) {
+		printStructured(ctx, issueEditView{Before: newIssueView(before), After: newIssueView(after)})
+		return
+	}
+
+	printIssueChanges(newIssueView(before), newIssueView(after))
+}
+
+func printIssueChanges(before, after issueView) {
+	color.Output = os.Stdout
+	changed := false
+
+	field := func(name, old, new string) {
+		if old == new {
+			return
+		}
+
+		changed = true
+		color.New(color.FgWhite).Printf("%s: ", name)
+		color.New(color.FgRed).Printf("%q", old)
+		fmt.Print(" -> ")
+		color.New(color.FgGreen).Printf("%q\n", new)
+	}
+
+	list := func(name string, old, new []string) {
+		added, removed := diffLists(old, new)
+		if len(added) == 0 && len(removed) == 0 {
+			return
+		}
+
+		changed = true
+		color.New(color.FgWhite).Printf("%s:", name)
+		for _, item := range removed {
+			color.New(color.FgRed).Printf(" -%s", item)
+		}
+		for _, item := range added {
+			color.New(color.FgGreen).Printf(" +%s", item)
+		}
+		fmt.Println()
+	}
+
+	field("Title", before.Title, after.Title)
+
+	if before.Body != after.Body {
+		changed = true
+		color.New(color.FgWhite).Print("Body: ")
+		fmt.Printf("%d lines -> %d lines\n", strings.Count(before.Body, "\n")+1, strings.Count(after.Body, "\n")+1)
+	}
+
+	list("Labels", before.Labels, after.Labels)
+	list("Assignees", before.Assignees, after.Assignees)
+	field("Milestone", before.Milestone, after.Milestone)
+
+	if !changed {
+		fmt.Printf("Issue %d unchanged.\n", after.Number)
+		return
+	}
+
+	fmt.Printf("Issue %d updated: %s\n", after.Number, after.URL)
+}
+
+func diffLists(old, new []string) ([]string, []string) {
+	inOld := map[string]bool{}
+	for _, item := range old {
+		inOld[item] = true
+	}
+
+	inNew := map[string]bool{}
+	for _, item := range new {
+		inNew[item] = true
+	}
+
+	added, removed := []string{}, []string{}
+
+	for _, item := range new {
+		if !inOld[item] {
+			added = append(added, item)
+		}
+	}
+
+	for _, item := range old {
+		if !inNew[item] {
+			removed = append(removed, item)
+		}
+	}
+
+	return added, removed
 }
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const keyringService = "barb"
+
+var errTokenNotFound = errors.New("no token stored")
+
+// tokenStore persists login tokens per host. The OS keyring is used when
+// its command line tool is available; otherwise tokens are kept in a file
+// encrypted with a passphrase.
+type tokenStore interface {
+	Name() string
+	Get(host string) (string, error)
+	Set(host, token string) error
+	Delete(host string) error
+}
+
+func tokenStores() []tokenStore {
+	stores := []tokenStore{}
+
+	if ks := newKeyringStore(); ks != nil {
+		stores = append(stores, ks)
+	}
+
+	return append(stores, &fileStore{path: filepath.Join(configDir(), "hosts.enc")})
+}
+
+func storedToken(host string) (string, string, error) {
+	for _, store := range tokenStores() {
+		token, err := store.Get(host)
+		if err == errTokenNotFound {
+			continue
+		} else if err != nil {
+			return "", "", fmt.Errorf("%s: %v", store.Name(), err)
+		}
+
+		return token, store.Name(), nil
+	}
+
+	return "", "", errTokenNotFound
+}
+
+type keyringStore struct {
+	tool string
+}
+
+func newKeyringStore() *keyringStore {
+	var tool string
+
+	switch runtime.GOOS {
+	case "darwin":
+		tool = "security"
+	case "linux", "freebsd", "openbsd", "netbsd":
+		tool = "secret-tool"
+	default:
+		return nil
+	}
+
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil
+	}
+
+	return &keyringStore{tool: path}
+}
+
+func (ks *keyringStore) Name() string {
+	return "keyring"
+}
+
+func (ks *keyringStore) service(host string) string {
+	return keyringService + ":" + host
+}
+
+func (ks *keyringStore) Get(host string) (string, error) {
+	var cmd *exec.Cmd
+
+	if filepath.Base(ks.tool) == "security" {
+		cmd = exec.Command(ks.tool, "find-generic-password", "-s", ks.service(host), "-w")
+	} else {
+		cmd = exec.Command(ks.tool, "lookup", "service", keyringService, "host", host)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ks.notFound(err) {
+			return "", errTokenNotFound
+		}
+
+		return "", fmt.Errorf("reading token from keyring: %v", exitMessage(err))
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", errTokenNotFound
+	}
+
+	return token, nil
+}
+
+// notFound tells a missing item apart from a locked keychain or an
+// unreachable secret service: security exits 44 for a missing item, and
+// secret-tool exits 1 without printing anything.
+func (ks *keyringStore) notFound(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+
+	if filepath.Base(ks.tool) == "security" {
+		return exitErr.ExitCode() == 44
+	}
+
+	return exitErr.ExitCode() == 1 && len(bytes.TrimSpace(exitErr.Stderr)) == 0
+}
+
+func exitMessage(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+		return fmt.Sprintf("%v: %s", err, bytes.TrimSpace(exitErr.Stderr))
+	}
+
+	return err.Error()
+}
+
+func (ks *keyringStore) Set(host, token string) error {
+	var cmd *exec.Cmd
+
+	if filepath.Base(ks.tool) == "security" {
+		// the command goes through stdin and the token is hex encoded, so
+		// neither shows up in the process list or needs quoting.
+		if strings.ContainsAny(host, " \t\r\n'\"\\") {
+			return fmt.Errorf("invalid host %q", host)
+		}
+
+		cmd = exec.Command(ks.tool, "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -a %s -s %s -X %s\n", host, ks.service(host), hex.EncodeToString([]byte(token))))
+	} else {
+		cmd = exec.Command(ks.tool, "store", "--label", "barb token for "+host, "service", keyringService, "host", host)
+		cmd.Stdin = strings.NewReader(token)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("storing token in keyring: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+func (ks *keyringStore) Delete(host string) error {
+	var cmd *exec.Cmd
+
+	if filepath.Base(ks.tool) == "security" {
+		cmd = exec.Command(ks.tool, "delete-generic-password", "-s", ks.service(host))
+	} else {
+		cmd = exec.Command(ks.tool, "clear", "service", keyringService, "host", host)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("removing token from keyring: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// fileStore keeps a JSON map of host to token, sealed with AES-GCM under a
+// key derived with scrypt from $BARB_PASSPHRASE or an interactive prompt.
+// The file layout is salt || nonce || ciphertext.
+type fileStore struct {
+	path       string
+	passphrase []byte
+}
+
+const (
+	fileStoreSaltSize = 16
+	fileStoreKeySize  = 32
+)
+
+func (fs *fileStore) Name() string {
+	return "encrypted file"
+}
+
+func (fs *fileStore) getPassphrase() ([]byte, error) {
+	if fs.passphrase != nil {
+		return fs.passphrase, nil
+	}
+
+	if p := os.Getenv("BARB_PASSPHRASE"); p != "" {
+		fs.passphrase = []byte(p)
+		return fs.passphrase, nil
+	}
+
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, errors.New("set BARB_PASSPHRASE to unlock the token file")
+	}
+
+	fmt.Fprintf(os.Stderr, "Passphrase for %s: ", fs.path)
+	p, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+
+	fs.passphrase = p
+	return p, nil
+}
+
+func (fs *fileStore) key(salt []byte) ([]byte, error) {
+	passphrase, err := fs.getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, fileStoreKeySize)
+}
+
+func (fs *fileStore) load() (map[string]string, error) {
+	tokens := map[string]string{}
+
+	content, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(content) < fileStoreSaltSize {
+		return nil, fmt.Errorf("%s is corrupt", fs.path)
+	}
+
+	salt := content[:fileStoreSaltSize]
+	key, err := fs.key(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := content[fileStoreSaltSize:]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%s is corrupt", fs.path)
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s; wrong passphrase?", fs.path)
+	}
+
+	if err := json.Unmarshal(plain, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (fs *fileStore) save(tokens map[string]string) error {
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, fileStoreSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+
+	key, err := fs.key(salt)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	content := append(salt, nonce...)
+	content = gcm.Seal(content, nonce, plain, nil)
+
+	if err := os.MkdirAll(filepath.Dir(fs.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fs.path, content, 0600)
+}
+
+func (fs *fileStore) Get(host string) (string, error) {
+	if _, err := os.Stat(fs.path); os.IsNotExist(err) {
+		return "", errTokenNotFound
+	}
+
+	tokens, err := fs.load()
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := tokens[host]
+	if !ok {
+		return "", errTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (fs *fileStore) Set(host, token string) error {
+	tokens, err := fs.load()
+	if err != nil {
+		return err
+	}
+
+	tokens[host] = token
+	return fs.save(tokens)
+}
+
+func (fs *fileStore) Delete(host string) error {
+	tokens, err := fs.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := tokens[host]; !ok {
+		return errTokenNotFound
+	}
+
+	delete(tokens, host)
+	return fs.save(tokens)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTool writes a shell script named name that records its arguments and
+// stdin next to itself and then runs body.
+func fakeTool(t *testing.T, name, body string) (*keyringStore, string) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, name)
+
+	script := "#!/bin/sh\necho \"$@\" > \"" + dir + "/args\"\ncat > \"" + dir + "/stdin\"\n" + body + "\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	return &keyringStore{tool: path}, dir
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func TestSecuritySetKeepsTokenOffCommandLine(t *testing.T) {
+	ks, dir := fakeTool(t, "security", "exit 0")
+
+	if err := ks.Set("github.com", "ghp_secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if args := readFile(t, filepath.Join(dir, "args")); strings.Contains(args, "ghp_secret") || strings.TrimSpace(args) != "-i" {
+		t.Fatalf("got arguments %q, want only -i", args)
+	}
+
+	want := "add-generic-password -U -a github.com -s barb:github.com -X " + hex.EncodeToString([]byte("ghp_secret")) + "\n"
+	if stdin := readFile(t, filepath.Join(dir, "stdin")); stdin != want {
+		t.Fatalf("got stdin %q, want %q", stdin, want)
+	}
+}
+
+func TestKeyringGetErrors(t *testing.T) {
+	for _, test := range []struct {
+		tool    string
+		body    string
+		message string
+	}{
+		{"security", "exit 44", ""},
+		{"security", "echo 'User interaction is not allowed.' >&2; exit 36", "User interaction is not allowed."},
+		{"secret-tool", "exit 1", ""},
+		{"secret-tool", "echo 'Cannot autolaunch D-Bus' >&2; exit 1", "Cannot autolaunch D-Bus"},
+	} {
+		ks, _ := fakeTool(t, test.tool, test.body)
+
+		_, err := ks.Get("github.com")
+		if test.message == "" && err != errTokenNotFound {
+			t.Errorf("%s %q: got %v, want errTokenNotFound", test.tool, test.body, err)
+		}
+
+		if test.message != "" && (err == nil || !strings.Contains(err.Error(), test.message)) {
+			t.Errorf("%s %q: got %v, want the tool's error", test.tool, test.body, err)
+		}
+	}
+}
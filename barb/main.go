@@ -6,16 +6,207 @@ import (
 	"github.com/urfave/cli"
 )
 
+func before(ctx *cli.Context) error {
+	if err := validateFormat(ctx); err != nil {
+		return err
+	}
+
+	repoOverride = ctx.GlobalString("repo")
+	remoteOverride = ctx.GlobalString("remote")
+	cacheDisabled = ctx.GlobalBool("no-cache")
+
+	return nil
+}
+
 func main() {
+	cfg, err := loadConfig(profileFromArgs(os.Args))
+	if err != nil {
+		exitError(err)
+	}
+
+	currentConfig = cfg
+
 	app := cli.NewApp()
 	app.Usage = "barbara is a github client"
 	app.Version = "0.1.0"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "f, format",
+			Usage: "Output format (text, json, yaml, template)",
+			Value: "text",
+		},
+		cli.StringFlag{
+			Name:  "template",
+			Usage: "Go text/template to render output with when --format is template",
+		},
+		cli.StringFlag{
+			Name:  "R, repo",
+			Usage: "Repository to operate on as owner/name, host/owner/name or a git URL",
+		},
+		cli.StringFlag{
+			Name:  "remote",
+			Usage: "Git remote to derive the repository from (default: barb.remote git config, then origin)",
+		},
+		cli.StringFlag{
+			Name:   "profile",
+			Usage:  "Configuration profile to use",
+			EnvVar: "BARB_PROFILE",
+		},
+		cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "Bypass the on-disk HTTP cache",
+		},
+		cli.BoolFlag{
+			Name:  "show-rate-limit",
+			Usage: "Print the remaining API quota to stderr when the command finishes",
+		},
+	}
+	app.Before = before
+	app.After = func(ctx *cli.Context) error {
+		if ctx.GlobalBool("show-rate-limit") {
+			lastRate.report()
+		}
+
+		return nil
+	}
 	app.Commands = []cli.Command{
+		{
+			Name:  "auth",
+			Usage: "Log in to and out of GitHub hosts",
+			Subcommands: []cli.Command{
+				{
+					Name:   "login",
+					Usage:  "Authenticate with the OAuth device flow and store the token in the keyring",
+					Action: authLogin,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "hostname",
+							Usage: "Host to log in to (default: the current repository's host)",
+						},
+						cli.StringFlag{
+							Name:  "client-id",
+							Usage: "OAuth application client id to use for the device flow",
+						},
+						cli.StringSliceFlag{
+							Name:  "s, scope",
+							Usage: "Additional scopes to request",
+						},
+						cli.BoolFlag{
+							Name:  "with-token",
+							Usage: "Read a token from stdin instead of using the device flow",
+						},
+					},
+				},
+				{
+					Name:   "logout",
+					Usage:  "Remove the stored token for a host",
+					Action: authLogout,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "hostname",
+							Usage: "Host to log out of (default: the current repository's host)",
+						},
+					},
+				},
+				{
+					Name:   "status",
+					Usage:  "Show which account and scopes barb is using",
+					Action: authStatus,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "hostname",
+							Usage: "Host to check (default: the current repository's host)",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:   "rate-limit",
+			Usage:  "Show the remaining API quota",
+			Action: rateLimit,
+		},
 		{
 			Name:      "issue",
 			ShortName: "i",
 			Usage:     "Subcommand trampoline for all issues",
 			Subcommands: []cli.Command{
+				{
+					Name:      "create",
+					Usage:     "Open an issue. Spawns $EDITOR; without --title the first line is the title",
+					ArgsUsage: "",
+					Action:    createIssue,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "t, title",
+							Usage: "Title of the issue",
+						},
+						cli.StringSliceFlag{
+							Name:  "l, label",
+							Usage: "Label to add; may be repeated",
+						},
+						cli.StringSliceFlag{
+							Name:  "a, assignee",
+							Usage: "User to assign (@me for yourself); may be repeated",
+						},
+						cli.StringFlag{
+							Name:  "m, milestone",
+							Usage: "Milestone to add the issue to (number or title)",
+						},
+						cli.StringFlag{
+							Name:  "T, template",
+							Usage: "Issue template to start from (name or file name)",
+						},
+						cli.StringFlag{
+							Name:  "F, body-file",
+							Usage: "Read the body from this file (- for stdin) instead of spawning $EDITOR",
+						},
+					},
+				},
+				{
+					Name:      "edit",
+					Usage:     "Edit an issue's title, body, labels, assignees or milestone",
+					ArgsUsage: "[id]",
+					Action:    editIssue,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "t, title",
+							Usage: "New title",
+						},
+						cli.BoolFlag{
+							Name:  "b, body",
+							Usage: "Edit the body in $EDITOR",
+						},
+						cli.StringFlag{
+							Name:  "F, body-file",
+							Usage: "Replace the body with the contents of this file (- for stdin)",
+						},
+						cli.StringSliceFlag{
+							Name:  "add-label",
+							Usage: "Label to add; may be repeated",
+						},
+						cli.StringSliceFlag{
+							Name:  "remove-label",
+							Usage: "Label to remove; may be repeated",
+						},
+						cli.StringSliceFlag{
+							Name:  "add-assignee",
+							Usage: "User to assign (@me for yourself); may be repeated",
+						},
+						cli.StringSliceFlag{
+							Name:  "remove-assignee",
+							Usage: "User to unassign (@me for yourself); may be repeated",
+						},
+						cli.StringFlag{
+							Name:  "m, milestone",
+							Usage: "Milestone to move the issue to (number or title)",
+						},
+						cli.BoolFlag{
+							Name:  "remove-milestone",
+							Usage: "Remove the issue from its milestone",
+						},
+					},
+				},
 				{
 					Name:      "reopen",
 					Usage:     "reopen an issue",
@@ -33,6 +224,12 @@ func main() {
 					Usage:     "get info on a single issue",
 					ArgsUsage: "[id]",
 					Action:    getIssue,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "raw",
+							Usage: "Print the body and comments as raw markdown",
+						},
+					},
 				},
 				{
 					Name:      "reply",
@@ -63,9 +260,41 @@ func main() {
 						},
 						cli.IntFlag{
 							Name:  "m, max-pages",
-							Usage: "Maximum number of list pages to fetch",
+							Usage: "Maximum number of list pages to fetch when no --limit is given",
 							Value: 5,
 						},
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "Fetch every page, ignoring --max-pages",
+						},
+						cli.StringFlag{
+							Name:  "a, author",
+							Usage: "Only show items created by this user (@me for yourself)",
+						},
+						cli.StringFlag{
+							Name:  "assignee",
+							Usage: "Only show items assigned to this user (@me for yourself)",
+						},
+						cli.StringSliceFlag{
+							Name:  "l, label",
+							Usage: "Only show items with this label; may be repeated",
+						},
+						cli.StringFlag{
+							Name:  "milestone",
+							Usage: "Only show items in this milestone (number or title)",
+						},
+						cli.StringFlag{
+							Name:  "mentions",
+							Usage: "Only show items mentioning this user (@me for yourself)",
+						},
+						cli.StringFlag{
+							Name:  "since",
+							Usage: "Only show items updated since this date, time or duration (e.g. 2018-09-01, 7d)",
+						},
+						cli.IntFlag{
+							Name:  "L, limit",
+							Usage: "Maximum number of items to show (0 for no limit)",
+						},
 					},
 				},
 			},
@@ -85,18 +314,74 @@ func main() {
 					Name:      "watch-hooks",
 					ShortName: "w",
 					Usage:     "Watch for hooks (like CI) to complete for a PR",
-					Action:    watch,
+					ArgsUsage: "[pull request id...]",
+					Description: "Exits 0 when every PR's statuses and check runs passed (or none were reported),\n" +
+						"   1 when any failed, 2 when --timeout ran out and 130 when interrupted.",
+					Action: watch,
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "i, interval",
+							Usage: "How often to poll",
+							Value: pollInterval,
+						},
+						cli.DurationFlag{
+							Name:  "t, timeout",
+							Usage: "Give up after this long (0 waits forever)",
+						},
+						cli.StringFlag{
+							Name:  "notify",
+							Usage: "When a PR finishes: bell, desktop, or a shell command run with BARB_PR_NUMBER, BARB_PR_STATE and BARB_PR_URL set",
+						},
+					},
 				},
 				{
 					Name:   "get",
 					Usage:  "Get state/comments for an PR",
 					Action: get,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "raw",
+							Usage: "Print the body and comments as raw markdown",
+						},
+					},
 				},
 				{
 					Name:   "reply",
 					Usage:  "Reply to a ticket",
 					Action: reply,
 				},
+				{
+					Name:      "review",
+					Usage:     "Review a PR, or queue inline comments for the review",
+					ArgsUsage: "[pull request id]",
+					Action:    reviewPR,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "approve",
+							Usage: "Submit an approving review",
+						},
+						cli.BoolFlag{
+							Name:  "request-changes",
+							Usage: "Submit a review requesting changes",
+						},
+						cli.BoolFlag{
+							Name:  "comment",
+							Usage: "Submit a review that only comments",
+						},
+						cli.StringFlag{
+							Name:  "m, message",
+							Usage: "Review summary or inline comment text instead of opening $EDITOR",
+						},
+						cli.StringFlag{
+							Name:  "a, add",
+							Usage: "Queue an inline comment on path:line for the next review",
+						},
+						cli.BoolFlag{
+							Name:  "discard",
+							Usage: "Drop the queued inline comments",
+						},
+					},
+				},
 				{
 					Name:   "list",
 					Usage:  "List PRs",
@@ -104,7 +389,7 @@ func main() {
 					Flags: []cli.Flag{
 						cli.StringFlag{
 							Name:  "s, state",
-							Usage: "State of prs (open, closed)",
+							Usage: "State of prs (open, closed, merged, all)",
 							Value: "open",
 						},
 						cli.StringFlag{
@@ -119,40 +404,239 @@ func main() {
 						},
 						cli.IntFlag{
 							Name:  "m, max-pages",
-							Usage: "Maximum number of list pages to fetch",
+							Usage: "Maximum number of list pages to fetch when no --limit is given",
 							Value: 5,
 						},
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "Fetch every page, ignoring --max-pages",
+						},
+						cli.StringFlag{
+							Name:  "a, author",
+							Usage: "Only show items created by this user (@me for yourself)",
+						},
+						cli.StringFlag{
+							Name:  "assignee",
+							Usage: "Only show items assigned to this user (@me for yourself)",
+						},
+						cli.StringSliceFlag{
+							Name:  "l, label",
+							Usage: "Only show items with this label; may be repeated",
+						},
+						cli.StringFlag{
+							Name:  "milestone",
+							Usage: "Only show items in this milestone (number or title)",
+						},
+						cli.StringFlag{
+							Name:  "mentions",
+							Usage: "Only show items mentioning this user (@me for yourself)",
+						},
+						cli.StringFlag{
+							Name:  "since",
+							Usage: "Only show items updated since this date, time or duration (e.g. 2018-09-01, 7d)",
+						},
+						cli.IntFlag{
+							Name:  "L, limit",
+							Usage: "Maximum number of items to show (0 for no limit)",
+						},
+						cli.StringFlag{
+							Name:  "base",
+							Usage: "Only show pull requests targeting this branch",
+						},
+						cli.StringFlag{
+							Name:  "head",
+							Usage: "Only show pull requests from this branch (branch or owner:branch)",
+						},
+						cli.BoolFlag{
+							Name:  "draft",
+							Usage: "Only show draft pull requests (--draft=false to hide them)",
+						},
+						cli.IntFlag{
+							Name:  "j, concurrency",
+							Usage: "Number of pull request statuses to fetch in parallel",
+							Value: 8,
+						},
+						cli.BoolFlag{
+							Name:  "r, reviews",
+							Usage: "Also fetch and show the review state of each pull request",
+						},
 					},
 				},
 				{
-					Name:  "create",
-					Usage: "Create a PR",
+					Name:      "create",
+					Usage:     "Create a PR. Spawns $EDITOR; without --title the first line is the title",
+					ArgsUsage: "[head branch or owner:branch; default: the current branch]",
 					Flags: []cli.Flag{
 						cli.StringFlag{
-							Name: "t, title",
+							Name:  "t, title",
+							Usage: "Title of the PR",
 						},
 						cli.StringFlag{
 							Name:  "b, base",
-							Value: "master",
+							Usage: "Branch to merge into (default: the repository's default branch)",
+						},
+						cli.BoolFlag{
+							Name:  "p, push",
+							Usage: "Push the branch and set its upstream first",
+						},
+						cli.StringFlag{
+							Name:  "T, template",
+							Usage: "Name of the pull request template to use",
+						},
+						cli.StringFlag{
+							Name:  "F, body-file",
+							Usage: "Read the body from this file (- for stdin) instead of opening $EDITOR",
+						},
+						cli.BoolFlag{
+							Name:  "d, draft",
+							Usage: "Open the PR as a draft",
+						},
+						cli.StringSliceFlag{
+							Name:  "r, reviewer",
+							Usage: "User or org/team to request a review from; may be repeated",
+						},
+						cli.StringSliceFlag{
+							Name:  "l, label",
+							Usage: "Label to add; may be repeated",
+						},
+						cli.StringSliceFlag{
+							Name:  "a, assignee",
+							Usage: "User to assign (@me for yourself); may be repeated",
+						},
+						cli.BoolFlag{
+							Name:  "no-maintainer-edit",
+							Usage: "Don't let maintainers push to the head branch",
 						},
 					},
 					Action: createPR,
 				},
 				{
-					Name:   "merge",
-					Usage:  "Merge a PR",
-					Action: mergePR,
+					Name:      "checks",
+					Usage:     "Show the statuses and check runs of a PR",
+					ArgsUsage: "[pull request id]",
+					Action:    checksPR,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "l, log",
+							Usage: "Page the log of this check run (name or id); GitHub Actions only",
+						},
+					},
+				},
+				{
+					Name:      "checkout",
+					Usage:     "Check out a PR in a local branch",
+					ArgsUsage: "[pull request id]",
+					Action:    checkoutPR,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "b, branch",
+							Usage: "Name of the local branch (default: the PR's head branch)",
+						},
+						cli.StringFlag{
+							Name:  "w, worktree",
+							Usage: "Check the branch out in a new git worktree at this path",
+						},
+					},
+				},
+				{
+					Name:      "ready",
+					Usage:     "Mark a draft PR ready for review",
+					ArgsUsage: "[pull request id]",
+					Action:    readyPR,
+				},
+				{
+					Name:      "draft",
+					Usage:     "Convert a PR back to a draft",
+					ArgsUsage: "[pull request id]",
+					Action:    draftPR,
+				},
+				{
+					Name:      "merge",
+					Usage:     "Merge a PR",
+					ArgsUsage: "[pull request id]",
+					Action:    mergePR,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "method",
+							Usage: "Merge method (merge, squash, rebase)",
+							Value: "merge",
+						},
+						cli.StringFlag{
+							Name:  "sha",
+							Usage: "Only merge if the PR's head is still this commit",
+						},
+						cli.BoolFlag{
+							Name:  "no-edit",
+							Usage: "Use the default commit message without opening $EDITOR",
+						},
+						cli.BoolFlag{
+							Name:  "force",
+							Usage: "Merge even if it is a draft or statuses or reviews are not satisfied",
+						},
+						cli.BoolFlag{
+							Name:  "d, delete-branch",
+							Usage: "Delete the head branch after merging",
+						},
+						cli.BoolFlag{
+							Name:  "when-green",
+							Usage: "Wait for statuses and required reviews, then merge",
+						},
+						cli.BoolFlag{
+							Name:  "auto",
+							Usage: "Enable GitHub's auto-merge instead of merging now",
+						},
+					},
 				},
 				{
 					Name:   "diff",
 					Usage:  "Get the diff for a PR",
 					Action: diffPR,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "n, line-numbers",
+							Usage: "Show base and head line numbers for use with pr comment",
+						},
+					},
+				},
+				{
+					Name:      "comment",
+					Usage:     "Comment on a line or range of lines in a PR's diff",
+					ArgsUsage: "[pull request id] [path:line or path:start-end]",
+					Action:    commentPR,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "side",
+							Usage: "Side of the diff the lines refer to: right (head) or left (base)",
+							Value: "right",
+						},
+						cli.StringFlag{
+							Name:  "m, message",
+							Usage: "Comment text instead of opening $EDITOR",
+						},
+					},
 				},
 			},
 		},
 	}
 
-	if err := app.Run(os.Args); err != nil {
+	if err := applyDefaults(app.Commands, "", cfg.Defaults); err != nil {
+		exitError(err)
+	}
+
+	args, err := expandAlias(app, os.Args, cfg.Aliases)
+	if err != nil {
+		exitError(err)
+	}
+
+	// urfave/cli would exit on an ExitCoder before app.After runs; the exit
+	// code is taken from the error app.Run returns instead.
+	cli.OsExiter = func(int) {}
+
+	if err := app.Run(args); err != nil {
+		if exitErr, ok := err.(cli.ExitCoder); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+
 		exitError(err)
 	}
 }
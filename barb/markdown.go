@@ -0,0 +1,675 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/fatih/color"
+)
+
+var (
+	ansiPattern      = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	rulePattern      = regexp.MustCompile(`^\s{0,3}((\*\s*){3,}|(-\s*){3,}|(_\s*){3,})$`)
+	setextPattern    = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	listPattern      = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	taskPattern      = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	delimiterPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+func terminalWidth() int {
+	if size, err := term.GetWinsize(os.Stdout.Fd()); err == nil && size.Width > 0 {
+		return int(size.Width)
+	}
+
+	return 80
+}
+
+// visibleLen is how many columns s takes once its escape codes are gone.
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(ansiPattern.ReplaceAllString(s, ""))
+}
+
+// printBody prints an issue or pull request body or a comment with every
+// line indented by indent, rendered for the terminal unless raw is set.
+func printBody(body, indent string, raw bool) {
+	lines := strings.Split(body, "\n")
+	if !raw {
+		lines = renderMarkdown(body, terminalWidth()-visibleLen(indent))
+	}
+
+	for _, l := range lines {
+		fmt.Println(indent + l)
+	}
+}
+
+// renderMarkdown renders the GitHub flavored markdown of bodies and comments
+// as lines of at most width columns. As on GitHub, a newline inside a
+// paragraph is a line break.
+func renderMarkdown(src string, width int) []string {
+	if width < 20 {
+		width = 20
+	}
+
+	lines := strings.Split(strings.Replace(src, "\r\n", "\n", -1), "\n")
+	out := []string{}
+
+	blank := func() {
+		if len(out) > 0 && out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		trimmed := strings.TrimSpace(l)
+
+		switch {
+		case trimmed == "":
+			blank()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			lang := strings.ToLower(strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])))
+
+			code := []string{}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+
+			out = append(out, renderCode(code, lang)...)
+
+		case strings.HasPrefix(trimmed, "<!--"):
+			// comments, such as the instructions in templates, are not shown;
+			// text after one on its closing line is.
+			from := strings.Index(l, "<!--") + len("<!--")
+			for ; i < len(lines); i, from = i+1, 0 {
+				if end := strings.Index(lines[i][from:], "-->"); end >= 0 {
+					if rest := strings.TrimLeft(lines[i][from+end+len("-->"):], " "); rest != "" {
+						lines[i] = rest
+						i--
+					}
+
+					break
+				}
+			}
+
+		case headingPattern.MatchString(trimmed):
+			m := headingPattern.FindStringSubmatch(trimmed)
+			blank()
+			out = append(out, renderHeading(m[2], len(m[1]), width)...)
+
+		case rulePattern.MatchString(l):
+			out = append(out, color.New(color.Faint).Sprint(strings.Repeat("─", width)))
+
+		case strings.HasPrefix(trimmed, ">"):
+			quoted := []string{}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			i--
+
+			// quoted replies are dimmed as a whole, whatever their own styles.
+			for _, q := range renderMarkdown(strings.Join(quoted, "\n"), width-2) {
+				out = append(out, color.New(color.Faint).Sprint("│ "+ansiPattern.ReplaceAllString(q, "")))
+			}
+
+		case strings.Contains(l, "|") && i+1 < len(lines) && delimiterPattern.MatchString(lines[i+1]):
+			rows := [][]string{splitRow(l)}
+			align := splitRow(lines[i+1])
+
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				rows = append(rows, splitRow(lines[i]))
+			}
+			i--
+
+			out = append(out, renderTable(rows, align)...)
+
+		case listPattern.MatchString(l):
+			m := listPattern.FindStringSubmatch(l)
+			out = append(out, renderListItem(m[1], m[2], m[3], width)...)
+
+		case i+1 < len(lines) && setextPattern.MatchString(lines[i+1]):
+			level := 2
+			if strings.HasPrefix(strings.TrimSpace(lines[i+1]), "=") {
+				level = 1
+			}
+
+			i++
+			blank()
+			out = append(out, renderHeading(trimmed, level, width)...)
+
+		default:
+			indent := strings.Repeat(" ", len(l)-len(strings.TrimLeft(l, " \t")))
+			out = append(out, wrapSpans(parseInline(trimmed, nil), width, indent, indent)...)
+		}
+	}
+
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+
+	return out
+}
+
+func renderHeading(text string, level, width int) []string {
+	attrs := []color.Attribute{color.Bold, color.FgWhite}
+	switch level {
+	case 1:
+		attrs = []color.Attribute{color.Bold, color.FgHiWhite, color.Underline}
+	case 2:
+		attrs = []color.Attribute{color.Bold, color.FgHiWhite}
+	}
+
+	return wrapSpans(parseInline(text, attrs), width, "", "")
+}
+
+func renderListItem(indent, marker, text string, width int) []string {
+	// nested lists step in by two columns, whatever the source used.
+	prefix := strings.Repeat("  ", len(strings.Replace(indent, "\t", "    ", -1))/2)
+
+	switch {
+	case taskPattern.MatchString(text):
+		m := taskPattern.FindStringSubmatch(text)
+		text = m[2]
+
+		if m[1] == " " {
+			prefix += "☐ "
+		} else {
+			prefix += color.New(color.FgGreen).Sprint("☑") + " "
+		}
+	case unicode.IsDigit(rune(marker[0])):
+		prefix += marker + " "
+	default:
+		prefix += "• "
+	}
+
+	return wrapSpans(parseInline(text, nil), width, prefix, strings.Repeat(" ", visibleLen(prefix)))
+}
+
+// splitRow splits a table row into its cells.
+func splitRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if !strings.HasSuffix(row, `\|`) {
+		row = strings.TrimSuffix(row, "|")
+	}
+
+	cells := []string{}
+	cell := ""
+
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell += "|"
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell))
+			cell = ""
+		default:
+			cell += string(row[i])
+		}
+	}
+
+	return append(cells, strings.TrimSpace(cell))
+}
+
+func renderTable(rows [][]string, align []string) []string {
+	rendered := [][]string{}
+	widths := []int{}
+
+	for r, row := range rows {
+		cells := []string{}
+
+		for c, cell := range row {
+			var attrs []color.Attribute
+			if r == 0 {
+				attrs = []color.Attribute{color.Bold}
+			}
+
+			text := renderSpans(parseInline(cell, attrs))
+			cells = append(cells, text)
+
+			if c >= len(widths) {
+				widths = append(widths, 0)
+			}
+
+			if n := visibleLen(text); n > widths[c] {
+				widths[c] = n
+			}
+		}
+
+		rendered = append(rendered, cells)
+	}
+
+	pad := func(text string, c int) string {
+		space := widths[c] - visibleLen(text)
+		a := ""
+		if c < len(align) {
+			a = align[c]
+		}
+
+		switch {
+		case strings.HasPrefix(a, ":") && strings.HasSuffix(a, ":"):
+			return strings.Repeat(" ", space/2) + text + strings.Repeat(" ", space-space/2)
+		case strings.HasSuffix(a, ":"):
+			return strings.Repeat(" ", space) + text
+		}
+
+		return text + strings.Repeat(" ", space)
+	}
+
+	sep := color.New(color.Faint).Sprint(" │ ")
+	out := []string{}
+
+	for r, cells := range rendered {
+		padded := []string{}
+		for c := range widths {
+			text := ""
+			if c < len(cells) {
+				text = cells[c]
+			}
+
+			padded = append(padded, pad(text, c))
+		}
+
+		out = append(out, strings.TrimRight(strings.Join(padded, sep), " "))
+
+		if r == 0 {
+			rules := []string{}
+			for _, w := range widths {
+				rules = append(rules, strings.Repeat("─", w))
+			}
+
+			out = append(out, color.New(color.Faint).Sprint(strings.Join(rules, "─┼─")))
+		}
+	}
+
+	return out
+}
+
+// span is a run of text in a single style.
+type span struct {
+	text  string
+	attrs []color.Attribute
+}
+
+func (s span) String() string {
+	if len(s.attrs) == 0 {
+		return s.text
+	}
+
+	return color.New(s.attrs...).Sprint(s.text)
+}
+
+func renderSpans(spans []span) string {
+	out := ""
+	for _, s := range spans {
+		out += s.String()
+	}
+
+	return out
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// closing finds the delimiter that closes one opened just before from: not
+// preceded by a space, and for underscores not inside a word.
+func closing(s string, from int, delim string) int {
+	for i := from + 1; i+len(delim) <= len(s); i++ {
+		if !strings.HasPrefix(s[i:], delim) || s[i-1] == ' ' {
+			continue
+		}
+
+		if delim[0] == '_' && i+len(delim) < len(s) && isWordByte(s[i+len(delim)]) {
+			continue
+		}
+
+		// a single delimiter must not be half of a double one.
+		if len(delim) == 1 && i+1 < len(s) && s[i+1] == delim[0] {
+			i++
+			continue
+		}
+
+		return i
+	}
+
+	return -1
+}
+
+// matching finds the bracket closing the one at s[open], allowing nesting.
+func matching(s string, open int, left, right byte) int {
+	depth := 0
+
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case left:
+			depth++
+		case right:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// parseInline splits a line of markdown into styled spans: code, emphasis,
+// strikethrough, links, bare URLs and @mentions.
+func parseInline(s string, attrs []color.Attribute) []span {
+	spans := []span{}
+	plain := ""
+
+	with := func(extra ...color.Attribute) []color.Attribute {
+		return append(append([]color.Attribute{}, attrs...), extra...)
+	}
+
+	emit := func(more ...span) {
+		if plain != "" {
+			spans = append(spans, span{plain, attrs})
+			plain = ""
+		}
+
+		spans = append(spans, more...)
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		atWord := i == 0 || !isWordByte(s[i-1])
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.IndexByte("\\`*_{}[]()#+-.!|~<>", rest[1]) >= 0:
+			plain += rest[1:2]
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			n := len(rest) - len(strings.TrimLeft(rest, "`"))
+			if end := strings.Index(rest[n:], rest[:n]); end >= 0 {
+				emit(span{strings.TrimSpace(rest[n : n+end]), with(color.FgYellow)})
+				i += end + 2*n
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__") || strings.HasPrefix(rest, "~~"):
+			delim := rest[:2]
+			if (delim != "__" || atWord) && len(rest) > 2 && rest[2] != ' ' {
+				if end := closing(s, i+1, delim); end > i+2 {
+					style := color.Bold
+					if delim == "~~" {
+						style = color.CrossedOut
+					}
+
+					emit(parseInline(s[i+2:end], with(style))...)
+					i = end + 2
+					continue
+				}
+			}
+
+		case rest[0] == '*' || rest[0] == '_':
+			if (rest[0] == '*' || atWord) && len(rest) > 1 && rest[1] != ' ' {
+				if end := closing(s, i, rest[:1]); end > i+1 {
+					emit(parseInline(s[i+1:end], with(color.Italic))...)
+					i = end + 1
+					continue
+				}
+			}
+
+		case rest[0] == '[' || strings.HasPrefix(rest, "!["):
+			image := rest[0] == '!'
+			open := i
+			if image {
+				open++
+			}
+
+			if end := matching(s, open, '[', ']'); end > 0 && end+1 < len(s) && s[end+1] == '(' {
+				if close := matching(s, end+1, '(', ')'); close > 0 {
+					text := s[open+1 : end]
+					target := strings.Fields(s[end+2 : close])
+
+					url := ""
+					if len(target) > 0 {
+						url = strings.Trim(target[0], "<>")
+					}
+
+					if image {
+						emit(span{"[image: " + text + "]", with(color.FgMagenta)})
+					} else {
+						emit(parseInline(text, with(color.FgBlue, color.Underline))...)
+					}
+
+					if url != "" && url != text {
+						emit(span{" (" + url + ")", with(color.Faint)})
+					}
+
+					i = close + 1
+					continue
+				}
+			}
+
+		case rest[0] == '<' && (strings.HasPrefix(rest, "<http://") || strings.HasPrefix(rest, "<https://")):
+			if end := strings.IndexByte(rest, '>'); end > 0 {
+				emit(span{rest[1:end], with(color.FgBlue, color.Underline)})
+				i += end + 1
+				continue
+			}
+
+		case atWord && (strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://")):
+			end := strings.IndexAny(rest, " \t<>")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			// sentence punctuation right after a URL is not part of it.
+			url := strings.TrimRight(rest[:end], ".,:;!?)")
+			emit(span{url, with(color.FgBlue, color.Underline)})
+			i += len(url)
+			continue
+
+		case rest[0] == '@' && atWord && len(rest) > 1 && isWordByte(rest[1]):
+			end := 1
+			for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '-' || rest[end] == '/') {
+				end++
+			}
+
+			emit(span{rest[:end], with(color.Bold, color.FgCyan)})
+			i += end
+			continue
+		}
+
+		plain += rest[:1]
+		i++
+	}
+
+	emit()
+	return spans
+}
+
+// wrapSpans lays spans out on lines of at most width columns, the first
+// starting with first and the others with rest. Words longer than a line
+// get one to themselves.
+func wrapSpans(spans []span, width int, first, rest string) []string {
+	lines := []string{}
+	current, prefix := "", first
+	used := visibleLen(first)
+	empty := true
+	pendingSpace := false
+
+	for _, s := range spans {
+		words := strings.Split(s.text, " ")
+
+		for j, word := range words {
+			if j > 0 {
+				pendingSpace = true
+			}
+
+			if word == "" {
+				continue
+			}
+
+			n := utf8.RuneCountInString(word)
+			if !empty && pendingSpace && used+1+n > width {
+				lines = append(lines, prefix+current)
+				current, prefix = "", rest
+				used = visibleLen(rest)
+				empty = true
+			}
+
+			if !empty && pendingSpace {
+				current += " "
+				used++
+			}
+
+			current += span{word, s.attrs}.String()
+			used += n
+			empty = false
+			pendingSpace = false
+		}
+	}
+
+	return append(lines, prefix+current)
+}
+
+var codeKeywords = map[string]string{
+	"go":     "break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false",
+	"python": "and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False self",
+	"js":     "async await break case catch class const continue default delete do else export extends finally for from function if import in instanceof let new of return switch this throw try typeof var void while yield null undefined true false interface type",
+	"sh":     "if then else elif fi case esac for while until do done in function return local export set unset echo exit",
+	"rust":   "as async await break const continue crate else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while",
+	"ruby":   "begin class def do else elsif end ensure false for if in module next nil not or rescue return self super then true unless until when while yield require",
+	"c":      "auto break case char const continue default do double else enum extern float for goto if int long return short signed sizeof static struct switch typedef union unsigned void volatile while class public private protected namespace template new delete bool true false nullptr include define",
+	"java":   "abstract boolean break byte case catch char class continue default do double else enum extends final finally float for if implements import instanceof int interface long new null package private protected public return short static super switch this throw throws try void while true false",
+	"sql":    "select from where and or not insert into values update set delete create table drop alter join left right inner outer on group by order having limit as null is in like",
+}
+
+var codeLanguages = map[string]string{
+	"golang": "go", "py": "python", "javascript": "js", "ts": "js", "typescript": "js", "jsx": "js", "tsx": "js", "json": "js",
+	"bash": "sh", "shell": "sh", "zsh": "sh", "console": "sh", "rs": "rust", "rb": "ruby", "cpp": "c", "c++": "c", "h": "c",
+	"kotlin": "java", "scala": "java",
+}
+
+func codeComment(lang string) string {
+	switch lang {
+	case "python", "sh", "ruby", "yaml", "yml", "toml", "dockerfile", "make", "makefile":
+		return "#"
+	case "sql":
+		return "--"
+	}
+
+	return "//"
+}
+
+// renderCode highlights a fenced code block: keywords, strings, numbers and
+// comments for common languages, and added and removed lines for diffs.
+func renderCode(code []string, lang string) []string {
+	if fields := strings.Fields(lang); len(fields) > 0 {
+		lang = fields[0]
+	}
+
+	if alias, ok := codeLanguages[lang]; ok {
+		lang = alias
+	}
+
+	keywords := map[string]bool{}
+	for _, kw := range strings.Fields(codeKeywords[lang]) {
+		keywords[kw] = true
+	}
+
+	gutter := color.New(color.Faint).Sprint("│ ")
+	out := []string{}
+
+	for _, l := range code {
+		l = strings.Replace(l, "\t", "    ", -1)
+
+		switch {
+		case lang == "diff" || lang == "patch":
+			out = append(out, "  "+gutter+highlightDiff(l))
+		case lang == "" || (len(keywords) == 0 && lang != "yaml" && lang != "yml" && lang != "toml"):
+			out = append(out, "  "+gutter+l)
+		default:
+			out = append(out, "  "+gutter+highlightLine(l, keywords, codeComment(lang)))
+		}
+	}
+
+	return out
+}
+
+func highlightDiff(l string) string {
+	switch {
+	case strings.HasPrefix(l, "+"):
+		return color.New(color.FgGreen).Sprint(l)
+	case strings.HasPrefix(l, "-"):
+		return color.New(color.FgRed).Sprint(l)
+	case strings.HasPrefix(l, "@"):
+		return color.New(color.FgCyan).Sprint(l)
+	}
+
+	return l
+}
+
+func highlightLine(l string, keywords map[string]bool, comment string) string {
+	out := ""
+
+	for i := 0; i < len(l); {
+		rest := l[i:]
+
+		switch {
+		case strings.HasPrefix(rest, comment):
+			return out + color.New(color.FgHiBlack).Sprint(rest)
+
+		case rest[0] == '"' || rest[0] == '\'' || rest[0] == '`':
+			end := 1
+			for end < len(rest) && rest[end] != rest[0] {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end < len(rest) {
+				end++
+			} else {
+				end = len(rest)
+			}
+
+			out += color.New(color.FgGreen).Sprint(rest[:end])
+			i += end
+
+		case isWordByte(rest[0]):
+			end := 0
+			for end < len(rest) && isWordByte(rest[end]) {
+				end++
+			}
+
+			word := rest[:end]
+			switch {
+			case keywords[word]:
+				out += color.New(color.FgHiBlue).Sprint(word)
+			case word[0] >= '0' && word[0] <= '9':
+				out += color.New(color.FgMagenta).Sprint(word)
+			default:
+				out += word
+			}
+
+			i += end
+
+		default:
+			out += rest[:1]
+			i++
+		}
+	}
+
+	return out
+}
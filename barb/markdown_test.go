@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestRenderMarkdown renders each testdata/markdown/*.md at 40 columns
+// without color and compares the result to the .golden file next to it.
+func TestRenderMarkdown(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = noColor })
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.md"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no markdown in testdata: %v", err)
+	}
+
+	for _, input := range inputs {
+		src, err := ioutil.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := strings.Join(renderMarkdown(string(src), 40), "\n") + "\n"
+		golden := strings.TrimSuffix(input, ".md") + ".golden"
+
+		if *updateGolden {
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != string(want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+var mergeMethods = []string{"merge", "squash", "rebase"}
+
+// GitHub works out whether a pull request merges cleanly in the background
+// after a push; this is how long and how often to ask before giving up.
+const (
+	mergeableTimeout  = 30 * time.Second
+	mergeableInterval = 2 * time.Second
+)
+
+// mergeReadiness is why a pull request can't be merged yet. Pending reasons
+// may resolve on their own, such as running checks; blockers will not.
+type mergeReadiness struct {
+	pending  []string
+	blockers []string
+}
+
+func (mr *mergeReadiness) ready() bool {
+	return len(mr.pending) == 0 && len(mr.blockers) == 0
+}
+
+func (mr *mergeReadiness) reasons() []string {
+	return append(append([]string{}, mr.blockers...), mr.pending...)
+}
+
+// checkMergeable looks at the pull request the way branch protection would:
+// its statuses and check runs, its reviews, and whether it merges cleanly.
+func checkMergeable(client *github.Client, owner, repo string, pr *github.PullRequest) (*mergeReadiness, error) {
+	mr := &mergeReadiness{}
+
+	if pr.GetMerged() {
+		mr.blockers = append(mr.blockers, "it is already merged")
+		return mr, nil
+	}
+
+	if pr.GetState() != "open" {
+		mr.blockers = append(mr.blockers, "it is closed")
+		return mr, nil
+	}
+
+	if pr.Mergeable == nil {
+		mr.pending = append(mr.pending, "GitHub is still checking whether it merges cleanly")
+	} else if !pr.GetMergeable() {
+		mr.blockers = append(mr.blockers, "it has merge conflicts")
+	}
+
+	checks, err := headChecks(context.Background(), client, owner, repo, pr.Head.GetSHA())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, check := range checks {
+		switch stateClass(check.State) {
+		case "pending":
+			mr.pending = append(mr.pending, fmt.Sprintf("%s is %s", check.Name, check.State))
+		case "failure":
+			mr.blockers = append(mr.blockers, fmt.Sprintf("%s is %s", check.Name, check.State))
+		}
+	}
+
+	reviews := []*github.PullRequestReview{}
+	err = eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		list, resp, err := client.PullRequests.ListReviews(context.Background(), owner, repo, pr.GetNumber(), &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, list...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reviewState(reviews) == "changes requested" {
+		mr.blockers = append(mr.blockers, "changes were requested")
+	}
+
+	required, err := requiredApprovals(client, owner, repo, pr.Base.GetRef())
+	if err != nil {
+		return nil, err
+	}
+
+	if approved := approvals(reviews); approved < required {
+		mr.pending = append(mr.pending, fmt.Sprintf("it has %d of %d required approving reviews", approved, required))
+	}
+
+	return mr, nil
+}
+
+// requiredApprovals is the number of approving reviews branch protection
+// requires on branch. Reading protection needs admin rights; without them
+// no approvals are assumed to be required and GitHub has the final word.
+func requiredApprovals(client *github.Client, owner, repo, branch string) (int, error) {
+	protection, resp, err := client.Repositories.GetBranchProtection(context.Background(), owner, repo, branch)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	if protection.RequiredPullRequestReviews == nil {
+		return 0, nil
+	}
+
+	if count := protection.RequiredPullRequestReviews.RequiredApprovingReviewCount; count > 0 {
+		return count, nil
+	}
+
+	return 1, nil
+}
+
+func approvals(reviews []*github.PullRequestReview) int {
+	count := 0
+	for _, state := range latestReviews(reviews) {
+		if state == "APPROVED" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// mergeMessage is the commit title and message GitHub would use for method,
+// offered in the editor as a starting point.
+func mergeMessage(client *github.Client, owner, repo string, pr *github.PullRequest, method string) (string, string, error) {
+	if method == "merge" {
+		return fmt.Sprintf("Merge pull request #%d from %s", pr.GetNumber(), pr.Head.GetLabel()), pr.GetTitle(), nil
+	}
+
+	messages := []string{}
+	err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		commits, resp, err := client.PullRequests.ListCommits(context.Background(), owner, repo, pr.GetNumber(), &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, commit := range commits {
+			messages = append(messages, "* "+strings.TrimSpace(commit.Commit.GetMessage()))
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return fmt.Sprintf("%s (#%d)", pr.GetTitle(), pr.GetNumber()), strings.Join(messages, "\n\n"), nil
+}
+
+// stripComments drops the '#' lines editMergeMessage adds as instructions.
+func stripComments(content string) string {
+	lines := []string{}
+
+	for _, l := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(l, "#") {
+			lines = append(lines, l)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func editMergeMessage(pr *github.PullRequest, method, title, message string) (string, string, error) {
+	content := fmt.Sprintf("%s\n\n%s\n\n# Merging PR #%d with the %s method.\n# The first line is the commit title; lines starting with '#' are ignored.\n# An empty message aborts the merge.\n", title, message, pr.GetNumber(), method)
+
+	edited, err := editText(content)
+	if err != nil {
+		return "", "", err
+	}
+
+	title, message = splitTitle(stripComments(edited))
+	if title == "" {
+		return "", "", fmt.Errorf("empty commit message; merge of PR %d aborted", pr.GetNumber())
+	}
+
+	return title, message, nil
+}
+
+func deleteHeadBranch(client *github.Client, pr *github.PullRequest) error {
+	head := pr.Head.Repo
+	if head == nil {
+		return fmt.Errorf("the head repository of PR %d no longer exists", pr.GetNumber())
+	}
+
+	_, err := client.Git.DeleteRef(context.Background(), head.Owner.GetLogin(), head.GetName(), "heads/"+pr.Head.GetRef())
+	return err
+}
+
+// waitMergeable polls the pull request until checkMergeable finds nothing
+// left to wait for, failing as soon as something blocks the merge for good
+// or the head moves away from sha.
+func waitMergeable(client *github.Client, owner, repo string, num int, sha string) (*github.PullRequest, error) {
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	var ready *github.PullRequest
+	waiting := ""
+
+	err := pollPR(ctx, client, owner, repo, num, pollInterval, func(pr *github.PullRequest) (bool, error) {
+		if pr.Head.GetSHA() != sha {
+			return false, fmt.Errorf("not merging PR %d: its head moved from %s to %s", num, sha, pr.Head.GetSHA())
+		}
+
+		mr, err := checkMergeable(client, owner, repo, pr)
+		if err != nil {
+			return false, err
+		}
+
+		if len(mr.blockers) > 0 {
+			return false, fmt.Errorf("not merging PR %d: %s", num, strings.Join(mr.blockers, "; "))
+		}
+
+		if mr.ready() {
+			ready = pr
+			return true, nil
+		}
+
+		if reasons := strings.Join(mr.pending, "; "); reasons != waiting {
+			fmt.Fprintf(os.Stderr, "Waiting: %s\n", reasons)
+			waiting = reasons
+		}
+
+		return false, nil
+	})
+
+	return ready, err
+}
+
+// waitComputed polls the pull request, like waitMergeable, until GitHub
+// knows whether it merges cleanly or mergeableTimeout passes, and returns
+// the latest copy. Checks and reviews are left for checkMergeable.
+func waitComputed(client *github.Client, owner, repo string, pr *github.PullRequest) (*github.PullRequest, error) {
+	if pr.Mergeable != nil {
+		return pr, nil
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	ctx, stop := context.WithTimeout(ctx, mergeableTimeout)
+	defer stop()
+
+	num, sha := pr.GetNumber(), pr.Head.GetSHA()
+	latest := pr
+
+	err := pollPR(ctx, client, owner, repo, num, mergeableInterval, func(pr *github.PullRequest) (bool, error) {
+		if pr.Head.GetSHA() != sha {
+			return false, fmt.Errorf("not merging PR %d: its head moved from %s to %s", num, sha, pr.Head.GetSHA())
+		}
+
+		latest = pr
+		return pr.Mergeable != nil, nil
+	})
+
+	// still unknown; checkMergeable reports it as pending.
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = nil
+	}
+
+	return latest, err
+}
+
+const enableAutoMergeMutation = `mutation($id: ID!, $method: PullRequestMergeMethod!, $headline: String, $body: String, $oid: GitObjectID) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method, commitHeadline: $headline, commitBody: $body, expectedHeadOid: $oid}) {
+    clientMutationId
+  }
+}`
+
+// enableAutoMerge turns on GitHub's own auto-merge, which merges the pull
+// request once branch protection is satisfied. The repository has to allow it.
+func enableAutoMerge(client *github.Client, pr *github.PullRequest, method, title, message, sha string) error {
+	variables := map[string]interface{}{
+		"id":     pr.GetNodeID(),
+		"method": strings.ToUpper(method),
+		"oid":    sha,
+	}
+
+	if method != "rebase" {
+		variables["headline"] = title
+		variables["body"] = message
+	}
+
+	return newGraphQLClient(client).query(enableAutoMergeMutation, variables, nil)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The view types below are the machine-readable schema emitted by --format.
+// Fields may be added, but existing fields must not be renamed or removed.
+
+type statusContextView struct {
+	Context     string `json:"context" yaml:"context"`
+	State       string `json:"state" yaml:"state"`
+	Description string `json:"description" yaml:"description"`
+	TargetURL   string `json:"target_url" yaml:"target_url"`
+}
+
+type statusView struct {
+	State    string              `json:"state" yaml:"state"`
+	Contexts []statusContextView `json:"contexts" yaml:"contexts"`
+}
+
+type commentView struct {
+	Author    string    `json:"author" yaml:"author"`
+	Body      string    `json:"body" yaml:"body"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	URL       string    `json:"url" yaml:"url"`
+}
+
+type pullRequestView struct {
+	Number       int            `json:"number" yaml:"number"`
+	Title        string         `json:"title" yaml:"title"`
+	State        string         `json:"state" yaml:"state"`
+	Draft        bool           `json:"draft" yaml:"draft"`
+	Author       string         `json:"author" yaml:"author"`
+	URL          string         `json:"url" yaml:"url"`
+	Base         string         `json:"base" yaml:"base"`
+	Head         string         `json:"head" yaml:"head"`
+	HeadSHA      string         `json:"head_sha" yaml:"head_sha"`
+	CreatedAt    time.Time      `json:"created_at" yaml:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" yaml:"updated_at"`
+	Body         string         `json:"body,omitempty" yaml:"body,omitempty"`
+	Labels       []string       `json:"labels" yaml:"labels"`
+	CommentCount int            `json:"comment_count" yaml:"comment_count"`
+	Status       *statusView    `json:"status,omitempty" yaml:"status,omitempty"`
+	Review       string         `json:"review,omitempty" yaml:"review,omitempty"`
+	Comments     []commentView  `json:"comments,omitempty" yaml:"comments,omitempty"`
+	Timeline     []timelineView `json:"timeline,omitempty" yaml:"timeline,omitempty"`
+}
+
+type issueView struct {
+	Number       int           `json:"number" yaml:"number"`
+	Title        string        `json:"title" yaml:"title"`
+	State        string        `json:"state" yaml:"state"`
+	Author       string        `json:"author" yaml:"author"`
+	URL          string        `json:"url" yaml:"url"`
+	Labels       []string      `json:"labels" yaml:"labels"`
+	Assignees    []string      `json:"assignees" yaml:"assignees"`
+	Milestone    string        `json:"milestone" yaml:"milestone"`
+	CreatedAt    time.Time     `json:"created_at" yaml:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at" yaml:"updated_at"`
+	Body         string        `json:"body,omitempty" yaml:"body,omitempty"`
+	CommentCount int           `json:"comment_count" yaml:"comment_count"`
+	Comments     []commentView `json:"comments,omitempty" yaml:"comments,omitempty"`
+}
+
+type fileView struct {
+	Filename  string `json:"filename" yaml:"filename"`
+	Status    string `json:"status" yaml:"status"`
+	Additions int    `json:"additions" yaml:"additions"`
+	Deletions int    `json:"deletions" yaml:"deletions"`
+	Patch     string `json:"patch" yaml:"patch"`
+}
+
+type resultView struct {
+	Number int    `json:"number" yaml:"number"`
+	Action string `json:"action" yaml:"action"`
+	URL    string `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+type watchResultView struct {
+	Number int         `json:"number" yaml:"number"`
+	State  string      `json:"state" yaml:"state"`
+	Checks []checkView `json:"checks,omitempty" yaml:"checks,omitempty"`
+}
+
+var outputFormats = []string{"text", "json", "yaml", "template"}
+
+func validateFormat(ctx *cli.Context) error {
+	format := ctx.GlobalString("format")
+
+	for _, f := range outputFormats {
+		if f == format {
+			if format == "template" && ctx.GlobalString("template") == "" {
+				return errors.New("--format template requires --template")
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid output format %q (valid: %s)", format, strings.Join(outputFormats, ", "))
+}
+
+func structuredOutput(ctx *cli.Context) bool {
+	format := ctx.GlobalString("format")
+	return format != "" && format != "text"
+}
+
+func printStructured(ctx *cli.Context, v interface{}) {
+	switch ctx.GlobalString("format") {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			exitError(err)
+		}
+	case "yaml":
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			exitError(err)
+		}
+
+		os.Stdout.Write(out)
+	case "template":
+		tmpl, err := template.New("output").Parse(ctx.GlobalString("template"))
+		if err != nil {
+			exitError(err)
+		}
+
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, v); err != nil {
+			exitError(err)
+		}
+
+		out := buf.String()
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+
+		fmt.Print(out)
+	}
+}
+
+func printResult(ctx *cli.Context, result resultView, text string) {
+	if structuredOutput(ctx) {
+		printStructured(ctx, result)
+		return
+	}
+
+	fmt.Println(text)
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
+
+func newStatusView(checks []checkView) *statusView {
+	view := &statusView{State: overallState(checks), Contexts: []statusContextView{}}
+
+	for _, check := range checks {
+		view.Contexts = append(view.Contexts, statusContextView{
+			Context:     check.Name,
+			State:       check.State,
+			Description: check.Description,
+			TargetURL:   check.URL,
+		})
+	}
+
+	return view
+}
+
+func newPullRequestView(pr *github.PullRequest) pullRequestView {
+	labels := []string{}
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
+	}
+
+	return pullRequestView{
+		Number:       pr.GetNumber(),
+		Title:        pr.GetTitle(),
+		State:        pr.GetState(),
+		Author:       pr.User.GetLogin(),
+		URL:          pr.GetHTMLURL(),
+		Base:         pr.Base.GetRef(),
+		Head:         pr.Head.GetRef(),
+		HeadSHA:      pr.Head.GetSHA(),
+		CreatedAt:    timeValue(pr.CreatedAt),
+		UpdatedAt:    timeValue(pr.UpdatedAt),
+		Body:         pr.GetBody(),
+		Labels:       labels,
+		CommentCount: pr.GetComments(),
+	}
+}
+
+func newIssueView(issue *github.Issue) issueView {
+	labels := []string{}
+	for _, label := range issue.Labels {
+		labels = append(labels, label.GetName())
+	}
+
+	assignees := []string{}
+	for _, user := range issue.Assignees {
+		assignees = append(assignees, user.GetLogin())
+	}
+
+	return issueView{
+		Number:       issue.GetNumber(),
+		Title:        issue.GetTitle(),
+		State:        issue.GetState(),
+		Author:       issue.User.GetLogin(),
+		URL:          issue.GetHTMLURL(),
+		Labels:       labels,
+		Assignees:    assignees,
+		Milestone:    issue.Milestone.GetTitle(),
+		CreatedAt:    timeValue(issue.CreatedAt),
+		UpdatedAt:    timeValue(issue.UpdatedAt),
+		Body:         issue.GetBody(),
+		CommentCount: issue.GetComments(),
+	}
+}
+
+func newFileView(file github.CommitFile) fileView {
+	return fileView{
+		Filename:  file.GetFilename(),
+		Status:    file.GetStatus(),
+		Additions: file.GetAdditions(),
+		Deletions: file.GetDeletions(),
+		Patch:     file.GetPatch(),
+	}
+}
+
+func newCommentView(user *github.User, body string, createdAt *time.Time, url string) commentView {
+	return commentView{
+		Author:    user.GetLogin(),
+		Body:      body,
+		CreatedAt: timeValue(createdAt),
+		URL:       url,
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+// restPageSize is the largest page the REST API hands out.
+const restPageSize = 100
+
+// pager walks the pages of a list call, REST or GraphQL, and decides when
+// to stop: after limit items or after pages pages, where zero means no
+// limit, or when the API says there are no more.
+type pager struct {
+	limit int
+	pages int
+	count int
+}
+
+// newPager reads --limit, --max-pages and --all. A limit is honored however
+// many pages it takes, so --max-pages only applies without one.
+func newPager(ctx *cli.Context) *pager {
+	p := &pager{limit: ctx.Int("limit"), pages: ctx.Int("max-pages")}
+	if p.limit > 0 || ctx.Bool("all") {
+		p.pages = 0
+	}
+
+	return p
+}
+
+// take counts one more item, and reports false once the limit is reached.
+func (p *pager) take() bool {
+	if p.full() {
+		return false
+	}
+
+	p.count++
+	return true
+}
+
+func (p *pager) full() bool {
+	return p.limit > 0 && p.count >= p.limit
+}
+
+// more reports whether to fetch another page after fetched of them.
+func (p *pager) more(fetched int, next bool) bool {
+	return next && !p.full() && (p.pages == 0 || fetched < p.pages)
+}
+
+// each calls fetch with the options for one page after another, following
+// Response.NextPage, which go-github reads from the Link header. fetch makes
+// the call, passes on each item it keeps for which take returns true, and
+// returns the response.
+func (p *pager) each(fetch func(opts github.ListOptions) (*github.Response, error)) error {
+	opts := github.ListOptions{Page: 1, PerPage: restPageSize}
+
+	for fetched := 1; ; fetched++ {
+		resp, err := fetch(opts)
+		if err != nil {
+			return err
+		}
+
+		if !p.more(fetched, resp.NextPage != 0) {
+			return nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// eachPage walks every page of a list call.
+func eachPage(fetch func(opts github.ListOptions) (*github.Response, error)) error {
+	return (&pager{}).each(fetch)
+}
@@ -4,9 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -16,32 +14,40 @@ import (
 	"github.com/urfave/cli"
 )
 
-func getPRs(client *github.Client, ctx *cli.Context, owner, repo string) ([]*github.PullRequest, error) {
-	newPulls := []*github.PullRequest{}
+// getPRs passes the pull requests matching the list flags to each, a page
+// at a time as they arrive. query is what prSearchQuery built for them.
+func getPRs(client *github.Client, ctx *cli.Context, owner, repo, query string, p *pager, each func([]*github.PullRequest) error) error {
+	if query != "" {
+		return searchPRs(client, ctx, query, p, each)
+	}
 
-	for page := 1; page < ctx.Int("max-pages"); page++ {
-		params := &github.PullRequestListOptions{
-			State:     ctx.String("state"),
-			Sort:      ctx.String("sort-by"),
-			Direction: ctx.String("direction"),
-			ListOptions: github.ListOptions{
-				Page: page,
-			},
-		}
+	head := ctx.String("head")
+	if head != "" && !strings.Contains(head, ":") {
+		head = owner + ":" + head
+	}
 
-		prs, _, err := client.PullRequests.List(context.Background(), owner, repo, params)
+	return p.each(func(opts github.ListOptions) (*github.Response, error) {
+		prs, resp, err := client.PullRequests.List(context.Background(), owner, repo, &github.PullRequestListOptions{
+			State:       ctx.String("state"),
+			Sort:        ctx.String("sort-by"),
+			Direction:   ctx.String("direction"),
+			Base:        ctx.String("base"),
+			Head:        head,
+			ListOptions: opts,
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		if len(prs) == 0 {
-			break
+		pulls := []*github.PullRequest{}
+		for _, pr := range prs {
+			if p.take() {
+				pulls = append(pulls, pr)
+			}
 		}
 
-		newPulls = append(newPulls, prs...)
-	}
-
-	return newPulls, nil
+		return resp, each(pulls)
+	})
 }
 
 func diffPR(ctx *cli.Context) {
@@ -72,6 +78,16 @@ func diffPR(ctx *cli.Context) {
 		exitError(err)
 	}
 
+	if structuredOutput(ctx) {
+		files := []fileView{}
+		for _, file := range commits.Files {
+			files = append(files, newFileView(file))
+		}
+
+		printStructured(ctx, files)
+		return
+	}
+
 	color.Output = os.Stdout
 
 	for _, file := range commits.Files {
@@ -79,18 +95,58 @@ func diffPR(ctx *cli.Context) {
 		fmt.Fprintln(color.Output, file.GetFilename())
 		line()
 
-		for _, line := range strings.Split(file.GetPatch(), "\n") {
-			switch line[0] {
-			case '+':
-				color.New(color.FgGreen).Println(line)
-			case '-':
-				color.New(color.FgRed).Println(line)
-			case '!':
-				color.New(color.FgYellow).Println(line)
-			default:
-				fmt.Fprintln(color.Output, line)
+		if ctx.Bool("line-numbers") {
+			if hunks, err := parsePatch(file.GetPatch()); err == nil {
+				printNumberedPatch(hunks)
+				continue
 			}
 		}
+
+		for _, line := range strings.Split(file.GetPatch(), "\n") {
+			printPatchLine(line)
+		}
+	}
+}
+
+func printPatchLine(line string) {
+	if line == "" {
+		fmt.Fprintln(color.Output)
+		return
+	}
+
+	switch line[0] {
+	case '+':
+		color.New(color.FgGreen).Println(line)
+	case '-':
+		color.New(color.FgRed).Println(line)
+	case '!':
+		color.New(color.FgYellow).Println(line)
+	case '@':
+		color.New(color.FgCyan).Println(line)
+	default:
+		fmt.Fprintln(color.Output, line)
+	}
+}
+
+// printNumberedPatch prefixes every line with its number in the base and
+// head versions of the file, the numbers `barb pr comment` expects.
+func printNumberedPatch(hunks []diffHunk) {
+	number := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+
+		return strconv.Itoa(n)
+	}
+
+	for _, hunk := range hunks {
+		fmt.Fprintf(color.Output, "%11s ", "")
+		printPatchLine(hunk.Header)
+
+		for _, dl := range hunk.Lines {
+			color.New(color.FgWhite).Printf("%5s %5s ", number(dl.OldLine), number(dl.NewLine))
+			printPatchLine(dl.Text)
+		}
 	}
 }
 
@@ -118,7 +174,7 @@ func closePR(ctx *cli.Context) {
 		exitError(err)
 	}
 
-	fmt.Printf("Pull request %s closed!\n", args[0])
+	printResult(ctx, resultView{Number: num, Action: "closed"}, fmt.Sprintf("Pull request %s closed!", args[0]))
 }
 
 func mergePR(ctx *cli.Context) {
@@ -138,83 +194,393 @@ func mergePR(ctx *cli.Context) {
 		exitError(err)
 	}
 
-	_, _, err = client.PullRequests.Merge(context.Background(), owner, repo, num, "", nil)
+	method := ctx.String("method")
+	if !contains(mergeMethods, method) {
+		exitError(fmt.Errorf("invalid merge method %q; use one of %s", method, strings.Join(mergeMethods, ", ")))
+	}
+
+	whenGreen, auto := ctx.Bool("when-green"), ctx.Bool("auto")
+	if whenGreen && auto {
+		exitError(errors.New("--when-green and --auto are mutually exclusive"))
+	}
+
+	if ctx.Bool("force") && (whenGreen || auto) {
+		exitError(errors.New("--force cannot be combined with --when-green or --auto"))
+	}
+
+	pr, _, err := client.PullRequests.Get(context.Background(), owner, repo, num)
 	if err != nil {
 		exitError(err)
 	}
 
-	fmt.Printf("PR #%s successfully merged!\n", args[0])
-}
+	sha := pr.Head.GetSHA()
+	if want := ctx.String("sha"); want != "" {
+		if !strings.HasPrefix(sha, want) {
+			exitError(fmt.Errorf("PR %d head is %s, not %s; someone pushed since", num, sha, want))
+		}
+	}
 
-func createPR(ctx *cli.Context) {
-	client := getClient()
+	// GitHub refuses to merge drafts; --auto can still queue one up.
+	if !ctx.Bool("force") && !auto && isDraft(client, owner, repo, num) {
+		exitError(fmt.Errorf("refusing to merge PR %d: it is a draft (run `barb pr ready %d` first, or use --force)", num, num))
+	}
 
-	args := ctx.Args()
+	if !ctx.Bool("force") && !whenGreen && !auto {
+		if pr, err = waitComputed(client, owner, repo, pr); err != nil {
+			exitError(err)
+		}
 
-	git, err := exec.LookPath("git")
-	if err != nil {
-		exitError(err)
+		mr, err := checkMergeable(client, owner, repo, pr)
+		if err != nil {
+			exitError(err)
+		}
+
+		if !mr.ready() {
+			exitError(fmt.Errorf("refusing to merge PR %d: %s (use --force to merge anyway)", num, strings.Join(mr.reasons(), "; ")))
+		}
+	}
+
+	// the message is settled up front so nobody has to wait around for an editor.
+	title, message := "", ""
+	if method != "rebase" {
+		title, message, err = mergeMessage(client, owner, repo, pr, method)
+		if err != nil {
+			exitError(err)
+		}
+
+		if !ctx.Bool("no-edit") {
+			title, message, err = editMergeMessage(pr, method, title, message)
+			if err != nil {
+				exitError(err)
+			}
+		}
+	}
+
+	if auto {
+		if err := enableAutoMerge(client, pr, method, title, message, sha); err != nil {
+			exitError(fmt.Errorf("enabling auto-merge on PR %d: %v (try --when-green instead)", num, err))
+		}
+
+		printResult(ctx, resultView{Number: num, Action: "auto-merge enabled", URL: pr.GetHTMLURL()}, fmt.Sprintf("PR #%d will be merged by GitHub when it is ready.", num))
+		return
+	}
+
+	if whenGreen {
+		if !structuredOutput(ctx) {
+			fmt.Printf("Waiting for PR #%d to be ready to merge...\n", num)
+		}
+
+		if pr, err = waitMergeable(client, owner, repo, num, sha); err != nil {
+			exitError(err)
+		}
 	}
 
-	out, err := exec.Command(git, "log", "-n", "1").Output()
+	// the head is pinned to the commit that was checked above.
+	result, _, err := client.PullRequests.Merge(context.Background(), owner, repo, num, message, &github.PullRequestOptions{
+		CommitTitle: title,
+		SHA:         sha,
+		MergeMethod: method,
+	})
 	if err != nil {
 		exitError(err)
 	}
 
-	if len(args) != 1 {
-		exitError(errors.New("invalid arguments"))
+	if ctx.Bool("delete-branch") {
+		if err := deleteHeadBranch(client, pr); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not delete branch %s: %v\n", pr.Head.GetLabel(), err)
+		} else if !structuredOutput(ctx) {
+			fmt.Printf("Deleted branch %s.\n", pr.Head.GetLabel())
+		}
 	}
 
-	lines := strings.Split(string(out), "\n")
-	trimmed := []string{}
+	printResult(ctx, resultView{Number: num, Action: "merged", URL: pr.GetHTMLURL()}, fmt.Sprintf("PR #%s successfully merged as %s!", args[0], result.GetSHA()))
+}
 
-	for _, l := range lines {
-		trimmed = append(trimmed, strings.TrimSpace(l))
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
 
-	title := ctx.String("title")
-	if title == "" {
-		title = trimmed[4]
+	return false
+}
+
+// newDraftPullRequest is NewPullRequest with the draft field go-github lacks.
+type newDraftPullRequest struct {
+	github.NewPullRequest
+	Draft bool `json:"draft,omitempty"`
+}
+
+func createPR(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) > 1 {
+		exitError(errors.New("invalid arguments"))
 	}
 
+	client := getClient()
+
 	owner, repo, err := repo()
 	if err != nil {
 		exitError(err)
 	}
 
-	f, err := ioutil.TempFile("", "barbara-edit")
-	if err != nil {
+	branch := ""
+	if len(args) == 1 {
+		branch = args[0]
+	} else if branch, err = currentBranch(); err != nil {
 		exitError(err)
 	}
-	f.Write([]byte(strings.Join(trimmed[6:], "\n")))
-	f.Close()
-	defer os.Remove(f.Name())
 
-	if err := runProgram(os.Getenv("EDITOR"), f.Name()); err != nil {
-		exitError(err)
+	// owner:branch names a branch in someone's fork.
+	head := branch
+	if i := strings.Index(branch, ":"); i >= 0 {
+		branch = branch[i+1:]
+	}
+
+	// a local branch's commits can describe the pull request, even once
+	// --push names it owner:branch.
+	local := head == branch && branchExists(branch)
+
+	base := ctx.String("base")
+	if base == "" {
+		r, _, err := client.Repositories.Get(context.Background(), owner, repo)
+		if err != nil {
+			exitError(err)
+		}
+
+		base = r.GetDefaultBranch()
+	}
+
+	if ctx.Bool("push") {
+		if head != branch {
+			exitError(errors.New("--push only works for local branches, not owner:branch"))
+		}
+
+		remote := pushRemote(branch)
+		if err := runGit("push", "--set-upstream", remote, branch); err != nil {
+			exitError(err)
+		}
+
+		// a branch pushed to a fork is named owner:branch in the API.
+		if r, err := parseRemoteURL(gitConfig("remote." + remote + ".url")); err == nil && !strings.EqualFold(r.Owner, owner) {
+			head = r.Owner + ":" + branch
+		}
 	}
 
-	content, err := ioutil.ReadFile(f.Name())
+	tmpl, err := pullRequestTemplate(ctx.String("template"))
 	if err != nil {
 		exitError(err)
 	}
 
-	if len(content) == 0 {
-		exitError(errors.New("prs must have content"))
+	title := ctx.String("title")
+	var body string
+
+	if file := ctx.String("body-file"); file != "" {
+		body, err = readBodyFile(file)
+		if err != nil {
+			exitError(err)
+		}
+	} else {
+		suggested, initial := title, tmpl
+
+		if local {
+			remote := findRemote(&repository{Host: currentHost(), Owner: owner, Name: repo})
+			commits, err := commitsSince(firstNonEmpty(remote, remoteName()), base, branch)
+			if err != nil {
+				exitError(err)
+			}
+
+			suggested, initial = describeCommits(commits, branch, title, tmpl)
+		}
+
+		if title == "" {
+			initial = suggested + "\n\n" + initial
+		}
+
+		content, err := editText(initial)
+		if err != nil {
+			exitError(err)
+		}
+
+		if title == "" {
+			title, body = splitTitle(content)
+		} else {
+			body = strings.TrimSpace(content)
+		}
+	}
+
+	if strings.TrimSpace(title) == "" {
+		exitError(errors.New("prs must have a title"))
 	}
 
-	pr, _, err := client.PullRequests.Create(context.Background(), owner, repo, &github.NewPullRequest{
-		Title: github.String(title),
-		Body:  github.String(string(content)),
-		Base:  github.String(ctx.String("base")),
-		Head:  github.String(args[0]),
+	req, err := client.NewRequest("POST", fmt.Sprintf("repos/%s/%s/pulls", owner, repo), &newDraftPullRequest{
+		NewPullRequest: github.NewPullRequest{
+			Title:               github.String(title),
+			Body:                github.String(body),
+			Base:                github.String(base),
+			Head:                github.String(head),
+			MaintainerCanModify: github.Bool(!ctx.Bool("no-maintainer-edit")),
+		},
+		Draft: ctx.Bool("draft"),
 	})
-
 	if err != nil {
 		exitError(err)
 	}
 
-	fmt.Printf("PR %d created!\n", pr.GetNumber())
+	// draft pull requests were a preview on older Enterprise releases.
+	req.Header.Set("Accept", "application/vnd.github.shadow-cat-preview+json")
+
+	pr := &github.PullRequest{}
+	if _, err := client.Do(context.Background(), req, pr); err != nil {
+		exitError(err)
+	}
+
+	if err := decoratePR(ctx, client, owner, repo, pr.GetNumber()); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: PR %d was created, but: %v\n", pr.GetNumber(), err)
+	}
+
+	printResult(ctx, resultView{Number: pr.GetNumber(), Action: "created", URL: pr.GetHTMLURL()}, fmt.Sprintf("PR %d created: %s", pr.GetNumber(), pr.GetHTMLURL()))
+}
+
+// describeCommits suggests a title and body from the commits on a branch: a
+// single commit's own message, or the branch name and a list of subjects.
+// The template, if any, goes first.
+func describeCommits(commits []commit, branch, title, tmpl string) (string, string) {
+	parts := []string{}
+	if strings.TrimSpace(tmpl) != "" {
+		parts = append(parts, strings.TrimSpace(tmpl))
+	}
+
+	switch len(commits) {
+	case 0:
+		return title, strings.Join(parts, "\n\n")
+	case 1:
+		if commits[0].Body != "" {
+			parts = append(parts, commits[0].Body)
+		}
+
+		return firstNonEmpty(title, commits[0].Subject), strings.Join(parts, "\n\n")
+	}
+
+	list := []string{}
+	for _, c := range commits {
+		list = append(list, "- "+c.Subject)
+	}
+
+	parts = append(parts, strings.Join(list, "\n"))
+	return firstNonEmpty(title, branch), strings.Join(parts, "\n\n")
+}
+
+// decoratePR applies --reviewer, --label and --assignee to a new PR.
+// Reviewers given as org/team are requested as teams.
+func decoratePR(ctx *cli.Context, client *github.Client, owner, repo string, num int) error {
+	reviewers := github.ReviewersRequest{}
+	for _, reviewer := range ctx.StringSlice("reviewer") {
+		if i := strings.Index(reviewer, "/"); i >= 0 {
+			reviewers.TeamReviewers = append(reviewers.TeamReviewers, reviewer[i+1:])
+			continue
+		}
+
+		login, err := resolveLogin(client, reviewer)
+		if err != nil {
+			return err
+		}
+
+		reviewers.Reviewers = append(reviewers.Reviewers, login)
+	}
+
+	if len(reviewers.Reviewers) > 0 || len(reviewers.TeamReviewers) > 0 {
+		if _, _, err := client.PullRequests.RequestReviewers(context.Background(), owner, repo, num, reviewers); err != nil {
+			return fmt.Errorf("requesting reviewers: %v", err)
+		}
+	}
+
+	labels := ctx.StringSlice("label")
+
+	assignees := []string{}
+	for _, assignee := range ctx.StringSlice("assignee") {
+		login, err := resolveLogin(client, assignee)
+		if err != nil {
+			return err
+		}
+
+		assignees = append(assignees, login)
+	}
+
+	if len(labels) == 0 && len(assignees) == 0 {
+		return nil
+	}
+
+	req := &github.IssueRequest{}
+	if len(labels) > 0 {
+		req.Labels = &labels
+	}
+
+	if len(assignees) > 0 {
+		req.Assignees = &assignees
+	}
+
+	if _, _, err := client.Issues.Edit(context.Background(), owner, repo, num, req); err != nil {
+		return fmt.Errorf("setting labels and assignees: %v", err)
+	}
+
+	return nil
+}
+
+// errNeedsREST means a pull request list asks for an order only the REST
+// API knows.
+var errNeedsREST = errors.New("sort order not supported by GraphQL")
+
+// queryPRs passes pull requests together with their statuses, reviews and
+// labels to each, fetched through GraphQL: one request per page instead of
+// one per pull request on top of the list.
+func queryPRs(client *github.Client, ctx *cli.Context, owner, repo, query string, p *pager, each func(pullRequestView)) error {
+	gc := newGraphQLClient(client)
+
+	emit := func(pull *pullSummary) {
+		each(pull.view())
+	}
+
+	sort := ctx.String("sort-by")
+
+	if query != "" {
+		if sort == "created" || sort == "updated" || sort == "comments" {
+			query += fmt.Sprintf(" sort:%s-%s", sort, ctx.String("direction"))
+		}
+
+		return gc.searchPullRequests(query, p, emit)
+	}
+
+	filter := pullFilter{
+		base:      ctx.String("base"),
+		direction: strings.ToUpper(ctx.String("direction")),
+	}
+
+	switch sort {
+	case "created":
+		filter.orderBy = "CREATED_AT"
+	case "updated":
+		filter.orderBy = "UPDATED_AT"
+	default:
+		return errNeedsREST
+	}
+
+	switch ctx.String("state") {
+	case "open":
+		filter.states = []string{"OPEN"}
+	case "closed":
+		filter.states = []string{"CLOSED", "MERGED"}
+	}
+
+	if head := ctx.String("head"); head != "" {
+		filter.headOwner, filter.head = owner, head
+		if i := strings.Index(head, ":"); i >= 0 {
+			filter.headOwner, filter.head = head[:i], head[i+1:]
+		}
+	}
+
+	return gc.pullRequests(owner, repo, filter, p, emit)
 }
 
 func listPRs(ctx *cli.Context) {
@@ -225,37 +591,145 @@ func listPRs(ctx *cli.Context) {
 		exitError(err)
 	}
 
-	pulls, err := getPRs(client, ctx, owner, repo)
+	switch state := ctx.String("state"); state {
+	case "open", "closed", "merged", "all":
+	default:
+		exitError(fmt.Errorf("invalid state %q", state))
+	}
+
+	query, err := prSearchQuery(client, ctx, owner, repo)
 	if err != nil {
 		exitError(err)
 	}
 
+	structured := structuredOutput(ctx)
 	color.Output = os.Stdout
 
-	for _, pull := range pulls {
-		color.New(color.FgWhite).Printf("[ %d ] ", pull.GetNumber())
-		color.New(color.FgBlue).Printf("(%s) ", pull.User.GetLogin())
-		fmt.Fprintf(os.Stdout, "%s", pull.GetTitle())
+	// text is printed as each page arrives; structured output is one document.
+	views := []pullRequestView{}
+	show := func(view pullRequestView) {
+		if structured {
+			views = append(views, view)
+		} else {
+			printPullLine(view, ctx.Bool("reviews"))
+		}
+	}
 
-		status, _, err := client.Repositories.GetCombinedStatus(context.Background(), owner, repo, pull.Head.GetSHA(), nil)
-		if err != nil {
-			exitError(err)
+	if err := eachPR(client, ctx, owner, repo, query, show); err != nil {
+		exitError(err)
+	}
+
+	if structured {
+		printStructured(ctx, views)
+	}
+}
+
+// eachPR passes the pull requests listPRs shows to show, through GraphQL
+// where it can and through the REST API otherwise.
+func eachPR(client *github.Client, ctx *cli.Context, owner, repo, query string, show func(pullRequestView)) error {
+	shown := 0
+	err := queryPRs(client, ctx, owner, repo, query, newPager(ctx), func(view pullRequestView) {
+		shown++
+		show(view)
+	})
+
+	// older Enterprise releases lack parts of the schema, which shows on the
+	// first page; after that, starting over would repeat what was printed.
+	if err != nil && shown == 0 {
+		if err != errNeedsREST {
+			fmt.Fprintf(os.Stderr, "warning: %v; falling back to the REST API\n", err)
+		}
+
+		return listPRsREST(client, ctx, owner, repo, query, show)
+	}
+
+	return err
+}
+
+// listPRsREST is listPRs for hosts without a usable GraphQL API, looking up
+// the statuses of each page of pull requests in parallel.
+func listPRsREST(client *github.Client, ctx *cli.Context, owner, repo, query string, show func(pullRequestView)) error {
+	reqCtx, cancel := interruptContext()
+	defer cancel()
+
+	warned := false
+
+	return getPRs(client, ctx, owner, repo, query, newPager(ctx), func(pulls []*github.PullRequest) error {
+		nums := []int{}
+		for _, pull := range pulls {
+			nums = append(nums, pull.GetNumber())
+		}
+
+		drafts, err := pullDrafts(client, owner, repo, nums)
+		if err != nil && !warned {
+			fmt.Fprintf(os.Stderr, "warning: could not tell which PRs are drafts: %v\n", err)
+			warned = true
+		}
+
+		results := fetchStatuses(reqCtx, client, owner, repo, pulls, ctx.Int("concurrency"), ctx.Bool("reviews"))
+
+		for i, pull := range pulls {
+			result := <-results[i]
+			if result.err != nil {
+				if reqCtx.Err() != nil {
+					return errors.New("interrupted")
+				}
+
+				return result.err
+			}
+
+			view := newPullRequestView(pull)
+			view.Draft = drafts[pull.GetNumber()]
+			view.Status = newStatusView(result.checks)
+			view.Review = result.review
+			show(view)
 		}
 
-		var stateColor *color.Color
+		return nil
+	})
+}
+
+func printPullLine(view pullRequestView, reviews bool) {
+	color.New(color.FgWhite).Printf("[ %d ] ", view.Number)
+	color.New(color.FgBlue).Printf("(%s) ", view.Author)
+	if view.Draft {
+		color.New(color.FgHiBlack).Print("[draft] ")
+	}
+	fmt.Fprintf(os.Stdout, "%s", view.Title)
+
+	for _, label := range view.Labels {
+		color.New(color.FgMagenta).Printf(" {%s}", label)
+	}
 
-		switch status.GetState() {
-		case "success":
-			stateColor = color.New(color.FgGreen)
-		case "pending":
-			stateColor = color.New(color.FgWhite)
-		case "error":
-			stateColor = color.New(color.FgYellow)
-		case "failure":
-			stateColor = color.New(color.FgRed)
+	var stateColor *color.Color
+	state := view.Status.State
+
+	switch state {
+	case "success":
+		stateColor = color.New(color.FgGreen)
+	case "pending":
+		stateColor = color.New(color.FgWhite)
+	case "error":
+		stateColor = color.New(color.FgYellow)
+	case "failure":
+		stateColor = color.New(color.FgRed)
+	default:
+		stateColor = color.New()
+	}
+
+	stateColor.Printf(" [ %s ]", state)
+
+	if reviews && view.Review != "" {
+		reviewColor := color.New(color.FgWhite)
+		switch view.Review {
+		case "approved":
+			reviewColor = color.New(color.FgGreen)
+		case "changes requested":
+			reviewColor = color.New(color.FgRed)
 		}
 
-		stateColor.Printf(" [ %s ]", status.GetState())
-		color.New(color.Reset).Print("\n")
+		reviewColor.Printf(" [ %s ]", view.Review)
 	}
+
+	color.New(color.Reset).Print("\n")
 }
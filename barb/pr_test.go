@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestDescribeCommits(t *testing.T) {
+	two := []commit{{Subject: "Add the parser"}, {Subject: "Fix a typo", Body: "in the parser"}}
+
+	for _, test := range []struct {
+		commits []commit
+		title   string
+		tmpl    string
+		want    [2]string
+	}{
+		{nil, "", "", [2]string{"", ""}},
+		{nil, "", "## Summary", [2]string{"", "## Summary"}},
+		{[]commit{{Subject: "Add the parser", Body: "It parses."}}, "", "", [2]string{"Add the parser", "It parses."}},
+		{[]commit{{Subject: "Add the parser"}}, "Parser", "## Summary", [2]string{"Parser", "## Summary"}},
+		{two, "", "", [2]string{"topic", "- Add the parser\n- Fix a typo"}},
+		{two, "Parser", "## Summary", [2]string{"Parser", "## Summary\n\n- Add the parser\n- Fix a typo"}},
+	} {
+		title, body := describeCommits(test.commits, "topic", test.title, test.tmpl)
+		if got := [2]string{title, body}; got != test.want {
+			t.Errorf("%d commits, title %q, template %q: got %q, want %q", len(test.commits), test.title, test.tmpl, got, test.want)
+		}
+	}
+}
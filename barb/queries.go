@@ -0,0 +1,534 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// pullSummaryFields is everything a pull request list or detail view shows,
+// including what REST would need a call per pull request for: the status
+// rollup of the head commit, the review decision, labels and comment count.
+const pullSummaryFields = `fragment pullSummary on PullRequest {
+  number
+  title
+  body
+  state
+  isDraft
+  url
+  createdAt
+  updatedAt
+  author { login }
+  baseRefName
+  headRefName
+  headRefOid
+  headRepositoryOwner { login }
+  reviewDecision
+  latestReviews(first: 100) { nodes { state } }
+  labels(first: 100) { nodes { name } }
+  comments { totalCount }
+  commits(last: 1) {
+    nodes {
+      commit {
+        statusCheckRollup {
+          state
+          contexts(first: 100) {
+            nodes {
+              __typename
+              ... on CheckRun { name status conclusion detailsUrl startedAt completedAt checkSuite { app { name } } }
+              ... on StatusContext { context state description targetUrl createdAt }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type actor struct {
+	Login string `json:"login"`
+}
+
+type nameNodes struct {
+	Nodes []struct {
+		Name string `json:"name"`
+	} `json:"nodes"`
+}
+
+func (nn nameNodes) names() []string {
+	names := []string{}
+	for _, node := range nn.Nodes {
+		names = append(names, node.Name)
+	}
+
+	return names
+}
+
+// rollupContext is a check run or a commit status in a status rollup.
+type rollupContext struct {
+	Typename string `json:"__typename"`
+
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion"`
+	DetailsURL  string     `json:"detailsUrl"`
+	StartedAt   *time.Time `json:"startedAt"`
+	CompletedAt *time.Time `json:"completedAt"`
+	CheckSuite  struct {
+		App struct {
+			Name string `json:"name"`
+		} `json:"app"`
+	} `json:"checkSuite"`
+
+	Context     string    `json:"context"`
+	State       string    `json:"state"`
+	Description string    `json:"description"`
+	TargetURL   string    `json:"targetUrl"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// view converts the GraphQL enums to the states the REST API reports.
+func (rc rollupContext) view() checkView {
+	if rc.Typename == "StatusContext" {
+		state := strings.ToLower(rc.State)
+		if state == "expected" {
+			state = "pending"
+		}
+
+		return checkView{
+			Name:        rc.Context,
+			Kind:        "status",
+			State:       state,
+			Description: rc.Description,
+			URL:         rc.TargetURL,
+			StartedAt:   &rc.CreatedAt,
+		}
+	}
+
+	state := strings.ToLower(rc.Conclusion)
+	switch rc.Status {
+	case "COMPLETED":
+	case "QUEUED", "IN_PROGRESS":
+		state = strings.ToLower(rc.Status)
+	default:
+		state = "pending"
+	}
+
+	return checkView{
+		Name:        rc.Name,
+		Kind:        "check",
+		State:       state,
+		URL:         rc.DetailsURL,
+		App:         rc.CheckSuite.App.Name,
+		StartedAt:   rc.StartedAt,
+		CompletedAt: rc.CompletedAt,
+	}
+}
+
+type statusRollup struct {
+	State    string `json:"state"`
+	Contexts struct {
+		Nodes []rollupContext `json:"nodes"`
+	} `json:"contexts"`
+}
+
+type pullSummary struct {
+	Number              int       `json:"number"`
+	Title               string    `json:"title"`
+	Body                string    `json:"body"`
+	State               string    `json:"state"`
+	IsDraft             bool      `json:"isDraft"`
+	URL                 string    `json:"url"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+	Author              actor     `json:"author"`
+	BaseRefName         string    `json:"baseRefName"`
+	HeadRefName         string    `json:"headRefName"`
+	HeadRefOid          string    `json:"headRefOid"`
+	HeadRepositoryOwner actor     `json:"headRepositoryOwner"`
+	ReviewDecision      string    `json:"reviewDecision"`
+	LatestReviews       struct {
+		Nodes []struct {
+			State string `json:"state"`
+		} `json:"nodes"`
+	} `json:"latestReviews"`
+	Labels   nameNodes `json:"labels"`
+	Comments struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"comments"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup *statusRollup `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+}
+
+// review is the review state as reviewState would report it. The review
+// decision is only set when branch protection requires reviews.
+func (ps *pullSummary) review() string {
+	switch ps.ReviewDecision {
+	case "APPROVED":
+		return "approved"
+	case "CHANGES_REQUESTED":
+		return "changes requested"
+	case "REVIEW_REQUIRED":
+		return "review required"
+	}
+
+	state := "none"
+	for _, review := range ps.LatestReviews.Nodes {
+		switch review.State {
+		case "CHANGES_REQUESTED":
+			return "changes requested"
+		case "APPROVED":
+			state = "approved"
+		}
+	}
+
+	return state
+}
+
+func (ps *pullSummary) rollup() *statusRollup {
+	if len(ps.Commits.Nodes) == 0 {
+		return nil
+	}
+
+	return ps.Commits.Nodes[0].Commit.StatusCheckRollup
+}
+
+// checks are the statuses and check runs on the head commit.
+func (ps *pullSummary) checks() []checkView {
+	checks := []checkView{}
+
+	if rollup := ps.rollup(); rollup != nil {
+		for _, node := range rollup.Contexts.Nodes {
+			checks = append(checks, node.view())
+		}
+	}
+
+	return checks
+}
+
+func (ps *pullSummary) status() *statusView {
+	view := newStatusView(ps.checks())
+
+	if rollup := ps.rollup(); rollup != nil {
+		view.State = strings.ToLower(rollup.State)
+		if view.State == "expected" {
+			view.State = "pending"
+		}
+	}
+
+	return view
+}
+
+func (ps *pullSummary) view() pullRequestView {
+	// REST has no merged state; a merged pull request is closed.
+	state := strings.ToLower(ps.State)
+	if state == "merged" {
+		state = "closed"
+	}
+
+	return pullRequestView{
+		Number:       ps.Number,
+		Title:        ps.Title,
+		State:        state,
+		Draft:        ps.IsDraft,
+		Author:       ps.Author.Login,
+		URL:          ps.URL,
+		Base:         ps.BaseRefName,
+		Head:         ps.HeadRefName,
+		HeadSHA:      ps.HeadRefOid,
+		CreatedAt:    ps.CreatedAt,
+		UpdatedAt:    ps.UpdatedAt,
+		Body:         ps.Body,
+		Labels:       ps.Labels.names(),
+		CommentCount: ps.Comments.TotalCount,
+		Status:       ps.status(),
+		Review:       ps.review(),
+	}
+}
+
+// pullFilter is what repository.pullRequests can filter and sort on;
+// anything else has to go through search.
+type pullFilter struct {
+	states    []string
+	base      string
+	head      string
+	headOwner string
+	orderBy   string
+	direction string
+}
+
+const pullRequestsQuery = `query($owner: String!, $repo: String!, $first: Int!, $after: String, $states: [PullRequestState!], $base: String, $head: String, $orderBy: IssueOrder) {
+  repository(owner: $owner, name: $repo) {
+    pullRequests(first: $first, after: $after, states: $states, baseRefName: $base, headRefName: $head, orderBy: $orderBy) {
+      pageInfo { hasNextPage endCursor }
+      nodes { ...pullSummary }
+    }
+  }
+}
+` + pullSummaryFields
+
+// pullRequests passes the pull requests of a repository to each as their
+// pages arrive.
+func (gc *graphQLClient) pullRequests(owner, repo string, filter pullFilter, p *pager, each func(*pullSummary)) error {
+	variables := map[string]interface{}{
+		"owner":   owner,
+		"repo":    repo,
+		"first":   pageSize(p.limit),
+		"orderBy": map[string]string{"field": filter.orderBy, "direction": filter.direction},
+	}
+
+	if len(filter.states) > 0 {
+		variables["states"] = filter.states
+	}
+
+	if filter.base != "" {
+		variables["base"] = filter.base
+	}
+
+	if filter.head != "" {
+		variables["head"] = filter.head
+	}
+
+	return gc.paginate(pullRequestsQuery, variables, p, func(data json.RawMessage) (*pageInfo, error) {
+		result := struct {
+			Repository struct {
+				PullRequests struct {
+					PageInfo pageInfo      `json:"pageInfo"`
+					Nodes    []pullSummary `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"repository"`
+		}{}
+
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+
+		for i, pull := range result.Repository.PullRequests.Nodes {
+			// headRefName matches the branch of any fork.
+			if filter.headOwner != "" && !strings.EqualFold(pull.HeadRepositoryOwner.Login, filter.headOwner) {
+				continue
+			}
+
+			if p.take() {
+				each(&result.Repository.PullRequests.Nodes[i])
+			}
+		}
+
+		return &result.Repository.PullRequests.PageInfo, nil
+	})
+}
+
+const searchPullRequestsQuery = `query($query: String!, $first: Int!, $after: String) {
+  search(query: $query, type: ISSUE, first: $first, after: $after) {
+    pageInfo { hasNextPage endCursor }
+    nodes { ...pullSummary }
+  }
+}
+` + pullSummaryFields
+
+// searchPullRequests passes the pull requests matching a search query such
+// as prSearchQuery builds to each as their pages arrive.
+func (gc *graphQLClient) searchPullRequests(query string, p *pager, each func(*pullSummary)) error {
+	return gc.paginate(searchPullRequestsQuery, map[string]interface{}{
+		"query": query,
+		"first": pageSize(p.limit),
+	}, p, func(data json.RawMessage) (*pageInfo, error) {
+		result := struct {
+			Search struct {
+				PageInfo pageInfo      `json:"pageInfo"`
+				Nodes    []pullSummary `json:"nodes"`
+			} `json:"search"`
+		}{}
+
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+
+		for i := range result.Search.Nodes {
+			if p.take() {
+				each(&result.Search.Nodes[i])
+			}
+		}
+
+		return &result.Search.PageInfo, nil
+	})
+}
+
+const pullRequestQuery = `query($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) { ...pullSummary }
+  }
+}
+` + pullSummaryFields
+
+// pullRequest is the summary of a single pull request.
+func (gc *graphQLClient) pullRequest(owner, repo string, num int) (*pullSummary, error) {
+	result := struct {
+		Repository struct {
+			PullRequest pullSummary `json:"pullRequest"`
+		} `json:"repository"`
+	}{}
+
+	err := gc.query(pullRequestQuery, map[string]interface{}{
+		"owner":  owner,
+		"repo":   repo,
+		"number": num,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.Repository.PullRequest, nil
+}
+
+type issueSummary struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	State     string    `json:"state"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Author    actor     `json:"author"`
+	Labels    nameNodes `json:"labels"`
+	Assignees struct {
+		Nodes []actor `json:"nodes"`
+	} `json:"assignees"`
+	Milestone struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	Comments struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"comments"`
+}
+
+func (is *issueSummary) view() issueView {
+	assignees := []string{}
+	for _, user := range is.Assignees.Nodes {
+		assignees = append(assignees, user.Login)
+	}
+
+	return issueView{
+		Number:       is.Number,
+		Title:        is.Title,
+		State:        strings.ToLower(is.State),
+		Author:       is.Author.Login,
+		URL:          is.URL,
+		Labels:       is.Labels.names(),
+		Assignees:    assignees,
+		Milestone:    is.Milestone.Title,
+		CreatedAt:    is.CreatedAt,
+		UpdatedAt:    is.UpdatedAt,
+		Body:         is.Body,
+		CommentCount: is.Comments.TotalCount,
+	}
+}
+
+// issueFilter mirrors the filters of the REST issue list.
+type issueFilter struct {
+	states    []string
+	labels    []string
+	author    string
+	assignee  string
+	mentioned string
+	milestone string
+	since     time.Time
+	orderBy   string
+	direction string
+}
+
+const issuesQuery = `query($owner: String!, $repo: String!, $first: Int!, $after: String, $states: [IssueState!], $labels: [String!], $filterBy: IssueFilters, $orderBy: IssueOrder) {
+  repository(owner: $owner, name: $repo) {
+    issues(first: $first, after: $after, states: $states, labels: $labels, filterBy: $filterBy, orderBy: $orderBy) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number
+        title
+        body
+        state
+        url
+        createdAt
+        updatedAt
+        author { login }
+        labels(first: 100) { nodes { name } }
+        assignees(first: 100) { nodes { login } }
+        milestone { title }
+        comments { totalCount }
+      }
+    }
+  }
+}`
+
+// issues passes the issues of a repository to each as their pages arrive.
+// Unlike the REST endpoint it never returns pull requests.
+func (gc *graphQLClient) issues(owner, repo string, filter issueFilter, p *pager, each func(*issueSummary)) error {
+	filterBy := map[string]interface{}{}
+
+	for key, value := range map[string]string{
+		"createdBy":       filter.author,
+		"assignee":        filter.assignee,
+		"mentioned":       filter.mentioned,
+		"milestoneNumber": filter.milestone,
+	} {
+		if value != "" {
+			filterBy[key] = value
+		}
+	}
+
+	if !filter.since.IsZero() {
+		filterBy["since"] = filter.since.Format(time.RFC3339)
+	}
+
+	variables := map[string]interface{}{
+		"owner":    owner,
+		"repo":     repo,
+		"first":    pageSize(p.limit),
+		"filterBy": filterBy,
+		"orderBy":  map[string]string{"field": filter.orderBy, "direction": filter.direction},
+	}
+
+	if len(filter.states) > 0 {
+		variables["states"] = filter.states
+	}
+
+	if len(filter.labels) > 0 {
+		variables["labels"] = filter.labels
+	}
+
+	return gc.paginate(issuesQuery, variables, p, func(data json.RawMessage) (*pageInfo, error) {
+		result := struct {
+			Repository struct {
+				Issues struct {
+					PageInfo pageInfo       `json:"pageInfo"`
+					Nodes    []issueSummary `json:"nodes"`
+				} `json:"issues"`
+			} `json:"repository"`
+		}{}
+
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+
+		for i := range result.Repository.Issues.Nodes {
+			if p.take() {
+				each(&result.Repository.Issues.Nodes[i])
+			}
+		}
+
+		return &result.Repository.Issues.PageInfo, nil
+	})
+}
+
+// pageSize avoids fetching a full page when fewer nodes are wanted.
+func pageSize(limit int) int {
+	if limit > 0 && limit < graphQLPageSize {
+		return limit
+	}
+
+	return graphQLPageSize
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+type pendingComment struct {
+	Path string `json:"path" yaml:"path"`
+	Line int    `json:"line" yaml:"line"`
+	Body string `json:"body" yaml:"body"`
+}
+
+// pendingPath is where inline comments wait until the review is submitted.
+// They live in the git directory so they never end up in a commit.
+func pendingPath(owner, repo string, num int) string {
+	dir := configDir()
+
+	if out, err := exec.Command("git", "rev-parse", "--absolute-git-dir").Output(); err == nil {
+		dir = filepath.Join(strings.TrimSpace(string(out)), "barb")
+	}
+
+	return filepath.Join(dir, "reviews", fmt.Sprintf("%s-%s-%d.json", owner, repo, num))
+}
+
+func loadPending(path string) ([]pendingComment, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	comments := []pendingComment{}
+	if err := json.Unmarshal(content, &comments); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return comments, nil
+}
+
+func savePending(path string, comments []pendingComment) error {
+	if len(comments) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	content, err := json.MarshalIndent(comments, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+// reviewEvent picks the review event from --approve, --request-changes and
+// --comment. It returns "" when none is set.
+func reviewEvent(ctx *cli.Context) (string, error) {
+	event := ""
+
+	for flag, e := range map[string]string{"approve": "APPROVE", "request-changes": "REQUEST_CHANGES", "comment": "COMMENT"} {
+		if !ctx.Bool(flag) {
+			continue
+		}
+
+		if event != "" {
+			return "", errors.New("--approve, --request-changes and --comment are mutually exclusive")
+		}
+
+		event = e
+	}
+
+	return event, nil
+}
+
+func reviewPR(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil {
+		exitError(err)
+	}
+
+	owner, repo, err := repo()
+	if err != nil {
+		exitError(err)
+	}
+
+	event, err := reviewEvent(ctx)
+	if err != nil {
+		exitError(err)
+	}
+
+	path := pendingPath(owner, repo, num)
+	pending, err := loadPending(path)
+	if err != nil {
+		exitError(err)
+	}
+
+	switch {
+	case ctx.Bool("discard"):
+		if err := savePending(path, nil); err != nil {
+			exitError(err)
+		}
+
+		printResult(ctx, resultView{Number: num, Action: "discarded"}, fmt.Sprintf("Discarded %d pending comments on PR %d.", len(pending), num))
+		return
+	case ctx.String("add") != "":
+		addPending(ctx, num, owner, repo, path, pending)
+		return
+	case event == "":
+		printPending(ctx, num, pending)
+		return
+	}
+
+	client := getClient()
+
+	files, err := pullFiles(client, owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	drafts := []*github.DraftReviewComment{}
+	for _, comment := range pending {
+		file, ok := files[comment.Path]
+		if !ok {
+			exitError(fmt.Errorf("%s is not changed by PR %d", comment.Path, num))
+		}
+
+		position, err := diffPosition(file, comment.Line)
+		if err != nil {
+			exitError(err)
+		}
+
+		drafts = append(drafts, &github.DraftReviewComment{
+			Path:     github.String(comment.Path),
+			Position: github.Int(position),
+			Body:     github.String(comment.Body),
+		})
+	}
+
+	body := ctx.String("message")
+	if !ctx.IsSet("message") {
+		body, err = editText("")
+		if err != nil {
+			exitError(err)
+		}
+	}
+	body = strings.TrimSpace(body)
+
+	if body == "" && (event == "REQUEST_CHANGES" || (event == "COMMENT" && len(drafts) == 0)) {
+		exitError(errors.New("a review summary is required"))
+	}
+
+	pr, _, err := client.PullRequests.Get(context.Background(), owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	review, _, err := client.PullRequests.CreateReview(context.Background(), owner, repo, num, &github.PullRequestReviewRequest{
+		CommitID: pr.Head.SHA,
+		Body:     github.String(body),
+		Event:    github.String(event),
+		Comments: drafts,
+	})
+	if err != nil {
+		exitError(err)
+	}
+
+	if err := savePending(path, nil); err != nil {
+		exitError(err)
+	}
+
+	action := strings.ToLower(strings.Replace(review.GetState(), "_", " ", -1))
+	printResult(ctx, resultView{Number: num, Action: action, URL: review.GetHTMLURL()}, fmt.Sprintf("Review on PR %d submitted (%s, %d comments)!", num, action, len(drafts)))
+}
+
+func addPending(ctx *cli.Context, num int, owner, repo, path string, pending []pendingComment) {
+	file, line, err := parseFileLine(ctx.String("add"))
+	if err != nil {
+		exitError(err)
+	}
+
+	client := getClient()
+
+	files, err := pullFiles(client, owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	cf, ok := files[file]
+	if !ok {
+		exitError(fmt.Errorf("%s is not changed by PR %d", file, num))
+	}
+
+	if _, err := diffPosition(cf, line); err != nil {
+		exitError(err)
+	}
+
+	body := ctx.String("message")
+	if !ctx.IsSet("message") {
+		body, err = editText("")
+		if err != nil {
+			exitError(err)
+		}
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		exitError(errors.New("no content to post"))
+	}
+
+	pending = append(pending, pendingComment{Path: file, Line: line, Body: body})
+	if err := savePending(path, pending); err != nil {
+		exitError(err)
+	}
+
+	printResult(ctx, resultView{Number: num, Action: "queued"}, fmt.Sprintf("Comment on %s:%d queued; %d pending on PR %d.", file, line, len(pending), num))
+}
+
+func printPending(ctx *cli.Context, num int, pending []pendingComment) {
+	if structuredOutput(ctx) {
+		if pending == nil {
+			pending = []pendingComment{}
+		}
+
+		printStructured(ctx, pending)
+		return
+	}
+
+	if len(pending) == 0 {
+		fmt.Printf("No pending review comments on PR %d.\n", num)
+		return
+	}
+
+	color.Output = os.Stdout
+
+	for _, comment := range pending {
+		color.New(color.FgWhite, color.Bold).Printf("%s:%d\n", comment.Path, comment.Line)
+		fmt.Println(comment.Body)
+		fmt.Println()
+	}
+}
+
+// lineComment is a review comment anchored by line and side rather than
+// diff position; go-github predates those fields.
+type lineComment struct {
+	Body      string `json:"body"`
+	CommitID  string `json:"commit_id"`
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"`
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+}
+
+func commentPR(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 2 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil {
+		exitError(err)
+	}
+
+	path, start, end, err := parseLocation(args[1])
+	if err != nil {
+		exitError(err)
+	}
+
+	side := strings.ToUpper(ctx.String("side"))
+	if side != "LEFT" && side != "RIGHT" {
+		exitError(fmt.Errorf("invalid side %q; use left or right", ctx.String("side")))
+	}
+
+	owner, repo, err := repo()
+	if err != nil {
+		exitError(err)
+	}
+
+	client := getClient()
+
+	files, err := pullFiles(client, owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	file, ok := files[path]
+	if !ok {
+		exitError(fmt.Errorf("%s is not changed by PR %d", path, num))
+	}
+
+	if err := checkLineRange(file, side, start, end); err != nil {
+		exitError(err)
+	}
+
+	body := ctx.String("message")
+	if !ctx.IsSet("message") {
+		body, err = editText("")
+		if err != nil {
+			exitError(err)
+		}
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		exitError(errors.New("no content to post"))
+	}
+
+	pr, _, err := client.PullRequests.Get(context.Background(), owner, repo, num)
+	if err != nil {
+		exitError(err)
+	}
+
+	lc := &lineComment{
+		Body:     body,
+		CommitID: pr.Head.GetSHA(),
+		Path:     path,
+		Line:     end,
+		Side:     side,
+	}
+
+	if start != end {
+		lc.StartLine = start
+		lc.StartSide = side
+	}
+
+	req, err := client.NewRequest("POST", fmt.Sprintf("repos/%s/%s/pulls/%d/comments", owner, repo, num), lc)
+	if err != nil {
+		exitError(err)
+	}
+
+	// older Enterprise releases only accept line and side under this preview.
+	req.Header.Set("Accept", "application/vnd.github.comfort-fade-preview+json")
+
+	comment := &github.PullRequestComment{}
+	if _, err := client.Do(context.Background(), req, comment); err != nil {
+		exitError(err)
+	}
+
+	printResult(ctx, resultView{Number: num, Action: "commented", URL: comment.GetHTMLURL()}, fmt.Sprintf("Comment on %s posted!", args[1]))
+}
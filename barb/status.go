@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/google/go-github/github"
+)
+
+type pullStatus struct {
+	checks []checkView
+	review string
+	err    error
+}
+
+// interruptContext returns a context that is cancelled on Ctrl-C, so
+// in-flight API calls unwind instead of the process dying mid-output.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+
+	go func() {
+		select {
+		case <-sigChan:
+			cancel()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(sigChan)
+	}()
+
+	return ctx, cancel
+}
+
+// fetchStatuses looks up the statuses and check runs (and optionally the review
+// state) of every pull request using at most concurrency workers. The
+// returned channels are in the same order as pulls and each receives exactly
+// one result, so callers can print in order as results arrive.
+func fetchStatuses(ctx context.Context, client *github.Client, owner, repo string, pulls []*github.PullRequest, concurrency int, reviews bool) []chan pullStatus {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]chan pullStatus, len(pulls))
+	for i := range results {
+		results[i] = make(chan pullStatus, 1)
+	}
+
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				results[i] <- fetchStatus(ctx, client, owner, repo, pulls[i], reviews)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		for i := range pulls {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				for ; i < len(pulls); i++ {
+					results[i] <- pullStatus{err: ctx.Err()}
+				}
+				return
+			}
+		}
+	}()
+
+	return results
+}
+
+func fetchStatus(ctx context.Context, client *github.Client, owner, repo string, pull *github.PullRequest, reviews bool) pullStatus {
+	// search results carry no head; each index is owned by a single worker
+	// and the caller only reads it after receiving the result.
+	if pull.Head == nil {
+		full, _, err := client.PullRequests.Get(ctx, owner, repo, pull.GetNumber())
+		if err != nil {
+			return pullStatus{err: err}
+		}
+
+		*pull = *full
+	}
+
+	checks, err := headChecks(ctx, client, owner, repo, pull.Head.GetSHA())
+	if err != nil {
+		return pullStatus{err: err}
+	}
+
+	ps := pullStatus{checks: checks}
+
+	if reviews {
+		list := []*github.PullRequestReview{}
+
+		err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+			page, resp, err := client.PullRequests.ListReviews(ctx, owner, repo, pull.GetNumber(), &opts)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, page...)
+			return resp, nil
+		})
+		if err != nil {
+			return pullStatus{err: err}
+		}
+
+		ps.review = reviewState(list)
+	}
+
+	return ps
+}
+
+// latestReviews maps each reviewer to the state of their latest approving
+// or blocking review; that is the only one GitHub counts.
+func latestReviews(reviews []*github.PullRequestReview) map[string]string {
+	latest := map[string]string{}
+
+	for _, review := range reviews {
+		switch review.GetState() {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latest[review.User.GetLogin()] = review.GetState()
+		}
+	}
+
+	return latest
+}
+
+// reviewState summarizes reviews the way GitHub does.
+func reviewState(reviews []*github.PullRequestReview) string {
+	state := "none"
+
+	for _, s := range latestReviews(reviews) {
+		switch s {
+		case "CHANGES_REQUESTED":
+			return "changes requested"
+		case "APPROVED":
+			state = "approved"
+		}
+	}
+
+	return state
+}
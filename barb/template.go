@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/term"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// stringList accepts both `labels: a, b` and `labels: [a, b]` in template
+// front matter, since GitHub allows either.
+type stringList []string
+
+func (sl *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*sl = list
+		return nil
+	}
+
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	*sl = splitList(s)
+	return nil
+}
+
+func splitList(s string) []string {
+	list := []string{}
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+type issueTemplate struct {
+	Name      string     `yaml:"name"`
+	About     string     `yaml:"about"`
+	Title     string     `yaml:"title"`
+	Labels    stringList `yaml:"labels"`
+	Assignees stringList `yaml:"assignees"`
+
+	File string `yaml:"-"`
+	Body string `yaml:"-"`
+}
+
+func parseIssueTemplate(path string) (*issueTemplate, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &issueTemplate{File: path, Body: string(content)}
+
+	text := strings.Replace(string(content), "\r\n", "\n", -1)
+	if strings.HasPrefix(text, "---\n") {
+		end := strings.Index(text[4:], "\n---")
+		if end < 0 {
+			return nil, fmt.Errorf("%s: unterminated front matter", path)
+		}
+
+		if err := yaml.Unmarshal([]byte(text[4:4+end]), tmpl); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		tmpl.Body = strings.TrimLeft(text[4+end+len("\n---"):], "\n")
+	}
+
+	if tmpl.Name == "" {
+		tmpl.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return tmpl, nil
+}
+
+// issueTemplates finds markdown issue templates in the places GitHub looks.
+func issueTemplates() ([]*issueTemplate, error) {
+	root := gitTopLevel()
+	if root == "" {
+		return nil, nil
+	}
+
+	paths := []string{}
+
+	for _, dir := range []string{".github", "docs", ""} {
+		matches, _ := filepath.Glob(filepath.Join(root, dir, "ISSUE_TEMPLATE", "*.md"))
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+
+		for _, name := range []string{"ISSUE_TEMPLATE.md", "issue_template.md"} {
+			if _, err := os.Stat(filepath.Join(root, dir, name)); err == nil {
+				paths = append(paths, filepath.Join(root, dir, name))
+			}
+		}
+	}
+
+	templates := []*issueTemplate{}
+	for _, path := range paths {
+		tmpl, err := parseIssueTemplate(path)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, tmpl)
+	}
+
+	return templates, nil
+}
+
+// pullRequestTemplate returns the body of the repository's pull request
+// template, or "" when it has none. With several templates in a
+// PULL_REQUEST_TEMPLATE directory, name picks one.
+func pullRequestTemplate(name string) (string, error) {
+	root := gitTopLevel()
+	if root == "" {
+		return "", nil
+	}
+
+	paths := []string{}
+
+	for _, dir := range []string{".github", "docs", ""} {
+		for _, file := range []string{"PULL_REQUEST_TEMPLATE.md", "pull_request_template.md"} {
+			paths = append(paths, filepath.Join(root, dir, file))
+		}
+
+		matches, _ := filepath.Glob(filepath.Join(root, dir, "PULL_REQUEST_TEMPLATE", "*.md"))
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+
+	for _, path := range paths {
+		if name != "" {
+			base := filepath.Base(path)
+			if base != name && strings.TrimSuffix(base, filepath.Ext(base)) != name {
+				continue
+			}
+		}
+
+		if content, err := ioutil.ReadFile(path); err == nil {
+			return string(content), nil
+		}
+	}
+
+	if name != "" {
+		return "", fmt.Errorf("no pull request template named %q", name)
+	}
+
+	return "", nil
+}
+
+func findIssueTemplate(templates []*issueTemplate, name string) (*issueTemplate, error) {
+	for _, tmpl := range templates {
+		base := filepath.Base(tmpl.File)
+		if strings.EqualFold(tmpl.Name, name) || base == name || strings.TrimSuffix(base, filepath.Ext(base)) == name {
+			return tmpl, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no issue template named %q", name)
+}
+
+// chooseIssueTemplate asks which template to use when there is more than
+// one. Zero selects a blank issue, as does having no terminal to ask on.
+// The prompt goes to stderr so it stays out of structured output.
+func chooseIssueTemplate(templates []*issueTemplate) (*issueTemplate, error) {
+	switch len(templates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return templates[0], nil
+	}
+
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintln(os.Stderr, "warning: several issue templates exist; starting from a blank issue, use --template to pick one")
+		return nil, nil
+	}
+
+	fmt.Fprintln(os.Stderr, "Choose an issue template:")
+	fmt.Fprintln(os.Stderr, "  0) Blank issue")
+	for i, tmpl := range templates {
+		if tmpl.About != "" {
+			fmt.Fprintf(os.Stderr, "  %d) %s - %s\n", i+1, tmpl.Name, tmpl.About)
+		} else {
+			fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, tmpl.Name)
+		}
+	}
+	fmt.Fprint(os.Stderr, "> ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 0 || choice > len(templates) {
+		return nil, errors.New("invalid template choice")
+	}
+
+	if choice == 0 {
+		return nil, nil
+	}
+
+	return templates[choice-1], nil
+}
+
+// splitTitle splits edited text into a title (the first line) and a body,
+// in the style of a git commit message.
+func splitTitle(content string) (string, string) {
+	content = strings.TrimLeft(content, "\n")
+	parts := strings.SplitN(content, "\n", 2)
+
+	title := strings.TrimSpace(parts[0])
+	body := ""
+	if len(parts) == 2 {
+		body = strings.TrimSpace(parts[1])
+	}
+
+	return title, body
+}
+
+func readBodyFile(path string) (string, error) {
+	if path == "-" {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(os.Stdin); err != nil {
+			return "", err
+		}
+
+		return buf.String(), nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	return string(content), err
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/google/go-github/github"
+)
+
+type threadView struct {
+	Path     string        `json:"path" yaml:"path"`
+	Line     int           `json:"line" yaml:"line"`
+	Resolved bool          `json:"resolved" yaml:"resolved"`
+	Outdated bool          `json:"outdated" yaml:"outdated"`
+	DiffHunk string        `json:"diff_hunk" yaml:"diff_hunk"`
+	Comments []commentView `json:"comments" yaml:"comments"`
+
+	review int64
+}
+
+// timelineView is one entry of a pull request's conversation: an issue
+// comment, or a review together with the inline threads it started.
+type timelineView struct {
+	Kind      string       `json:"kind" yaml:"kind"`
+	Author    string       `json:"author" yaml:"author"`
+	State     string       `json:"state,omitempty" yaml:"state,omitempty"`
+	Body      string       `json:"body" yaml:"body"`
+	CreatedAt time.Time    `json:"created_at" yaml:"created_at"`
+	URL       string       `json:"url" yaml:"url"`
+	Threads   []threadView `json:"threads,omitempty" yaml:"threads,omitempty"`
+}
+
+type threadState struct {
+	resolved bool
+	outdated bool
+}
+
+const reviewThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          isResolved
+          isOutdated
+          comments(first: 1) { nodes { databaseId } }
+        }
+      }
+    }
+  }
+}`
+
+// reviewThreadStates maps the ID of each thread's first comment to whether
+// the thread is resolved or outdated; the REST API knows neither.
+func reviewThreadStates(client *github.Client, owner, repo string, num int) (map[int64]threadState, error) {
+	states := map[int64]threadState{}
+
+	err := newGraphQLClient(client).paginate(reviewThreadsQuery, map[string]interface{}{
+		"owner":  owner,
+		"repo":   repo,
+		"number": num,
+	}, &pager{}, func(data json.RawMessage) (*pageInfo, error) {
+		result := struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						PageInfo pageInfo `json:"pageInfo"`
+						Nodes    []struct {
+							IsResolved bool `json:"isResolved"`
+							IsOutdated bool `json:"isOutdated"`
+							Comments   struct {
+								Nodes []struct {
+									DatabaseID int64 `json:"databaseId"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}{}
+
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+
+		threads := result.Repository.PullRequest.ReviewThreads
+		for _, node := range threads.Nodes {
+			if len(node.Comments.Nodes) > 0 {
+				states[node.Comments.Nodes[0].DatabaseID] = threadState{resolved: node.IsResolved, outdated: node.IsOutdated}
+			}
+		}
+
+		return &threads.PageInfo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+// hunkLine is the line a review comment is attached to: the last line of
+// the diff hunk GitHub stores with it.
+func hunkLine(hunk string) int {
+	hunks, err := parsePatch(hunk)
+	if err != nil || len(hunks) == 0 {
+		return 0
+	}
+
+	lines := hunks[len(hunks)-1].Lines
+	if len(lines) == 0 {
+		return 0
+	}
+
+	last := lines[len(lines)-1]
+	if last.NewLine != 0 {
+		return last.NewLine
+	}
+
+	return last.OldLine
+}
+
+// pullTimeline merges issue comments, reviews and inline review threads into
+// one chronological list.
+func pullTimeline(client *github.Client, owner, repo string, num int, comments []*github.PullRequestComment) ([]timelineView, error) {
+	entries := []timelineView{}
+
+	err := eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		list, resp, err := client.Issues.ListComments(context.Background(), owner, repo, num, &github.IssueListCommentsOptions{
+			ListOptions: opts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, comment := range list {
+			entries = append(entries, timelineView{
+				Kind:      "comment",
+				Author:    comment.User.GetLogin(),
+				Body:      comment.GetBody(),
+				CreatedAt: timeValue(comment.CreatedAt),
+				URL:       comment.GetHTMLURL(),
+			})
+		}
+
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := []*github.PullRequestReview{}
+	err = eachPage(func(opts github.ListOptions) (*github.Response, error) {
+		list, resp, err := client.PullRequests.ListReviews(context.Background(), owner, repo, num, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, list...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := reviewThreadStates(client, owner, repo, num)
+	if err != nil {
+		// older Enterprise releases lack these fields; fall back to what REST knows.
+		fmt.Fprintf(os.Stderr, "warning: could not load thread states: %v\n", err)
+		states = nil
+	}
+
+	threads := []*threadView{}
+	byRoot := map[int64]*threadView{}
+
+	for _, comment := range comments {
+		view := newCommentView(comment.User, comment.GetBody(), comment.CreatedAt, comment.GetHTMLURL())
+
+		if thread, ok := byRoot[comment.GetInReplyTo()]; ok {
+			thread.Comments = append(thread.Comments, view)
+			byRoot[comment.GetID()] = thread
+			continue
+		}
+
+		thread := &threadView{
+			Path:     comment.GetPath(),
+			Line:     hunkLine(comment.GetDiffHunk()),
+			Outdated: comment.Position == nil,
+			DiffHunk: comment.GetDiffHunk(),
+			Comments: []commentView{view},
+			review:   comment.GetPullRequestReviewID(),
+		}
+
+		if state, ok := states[comment.GetID()]; ok {
+			thread.Resolved = state.resolved
+			thread.Outdated = state.outdated
+		}
+
+		threads = append(threads, thread)
+		byRoot[comment.GetID()] = thread
+	}
+
+	reviewIndex := map[int64]int{}
+	for _, review := range reviews {
+		// pending reviews belong to their author and have no place in the timeline.
+		if review.GetState() == "PENDING" {
+			continue
+		}
+
+		reviewIndex[review.GetID()] = len(entries)
+		entries = append(entries, timelineView{
+			Kind:      "review",
+			Author:    review.User.GetLogin(),
+			State:     strings.ToLower(strings.Replace(review.GetState(), "_", " ", -1)),
+			Body:      review.GetBody(),
+			CreatedAt: timeValue(review.SubmittedAt),
+			URL:       review.GetHTMLURL(),
+		})
+	}
+
+	for _, thread := range threads {
+		if i, ok := reviewIndex[thread.review]; ok {
+			entries[i].Threads = append(entries[i].Threads, *thread)
+			continue
+		}
+
+		entries = append(entries, timelineView{
+			Kind:      "review",
+			Author:    thread.Comments[0].Author,
+			State:     "commented",
+			CreatedAt: thread.Comments[0].CreatedAt,
+			URL:       thread.Comments[0].URL,
+			Threads:   []threadView{*thread},
+		})
+	}
+
+	// every reply to a thread is filed as an empty review of its own.
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.Kind != "review" || entry.State != "commented" || entry.Body != "" || len(entry.Threads) > 0 {
+			kept = append(kept, entry)
+		}
+	}
+	entries = kept
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	return entries, nil
+}
+
+func printTimeline(entries []timelineView, raw bool) {
+	for _, entry := range entries {
+		fmt.Println()
+		line()
+
+		switch entry.Kind {
+		case "review":
+			reviewColor := color.New(color.FgWhite)
+			switch entry.State {
+			case "approved":
+				reviewColor = color.New(color.FgGreen)
+			case "changes requested":
+				reviewColor = color.New(color.FgRed)
+			}
+
+			reviewColor.Printf("Review by %s: %s\n", entry.Author, entry.State)
+		default:
+			color.New(color.FgWhite).Printf("From: %s\n", entry.Author)
+		}
+
+		color.New(color.FgWhite).Printf("Date: %s\n", entry.CreatedAt.Local())
+		line()
+
+		if entry.Body != "" {
+			fmt.Println()
+			printBody(entry.Body, "", raw)
+		}
+
+		for _, thread := range entry.Threads {
+			printThread(thread, raw)
+		}
+	}
+}
+
+// threadContext is how many lines of the diff hunk are shown above a thread.
+const threadContext = 4
+
+func printThread(thread threadView, raw bool) {
+	fmt.Println()
+
+	header := color.New(color.FgCyan, color.Bold)
+	header.Printf("%s:%d", thread.Path, thread.Line)
+	if thread.Outdated {
+		color.New(color.FgYellow).Print(" [outdated]")
+	}
+	if thread.Resolved {
+		color.New(color.FgGreen).Print(" [resolved]")
+	}
+	fmt.Println()
+
+	hunk := strings.Split(strings.TrimRight(thread.DiffHunk, "\n"), "\n")
+	if len(hunk) > threadContext {
+		hunk = hunk[len(hunk)-threadContext:]
+	}
+
+	for _, text := range hunk {
+		fmt.Fprint(color.Output, "    ")
+		printPatchLine(text)
+	}
+
+	// resolved threads are collapsed to their first comment.
+	comments := thread.Comments
+	if thread.Resolved && len(comments) > 1 {
+		comments = comments[:1]
+	}
+
+	for _, comment := range comments {
+		fmt.Println()
+		color.New(color.FgWhite).Printf("  %s (%s):\n", comment.Author, comment.CreatedAt.Local().Format(time.RFC822))
+		printBody(comment.Body, "  ", raw)
+	}
+
+	if len(comments) < len(thread.Comments) {
+		color.New(color.FgWhite).Printf("  ... %d more replies\n", len(thread.Comments)-len(comments))
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+const (
+	maxRetries       = 5
+	maxBackoff       = 30 * time.Second
+	maxRateLimitWait = 5 * time.Minute
+)
+
+// rateState is the most recent X-RateLimit-* values seen on any response,
+// reported by --show-rate-limit when the command exits.
+type rateState struct {
+	mutex     sync.Mutex
+	seen      bool
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+var lastRate = &rateState{}
+
+// retrySleep waits between attempts; tests swap it out to skip the wait.
+var retrySleep = sleepContext
+
+func (rs *rateState) update(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+
+	remaining, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	rs.seen = true
+	rs.limit = limit
+	rs.remaining = remaining
+	rs.reset = time.Unix(reset, 0)
+}
+
+func (rs *rateState) report() {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	if !rs.seen {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "API quota: %d/%d remaining, resets at %s\n", rs.remaining, rs.limit, rs.reset.Local().Format(time.Kitchen))
+}
+
+// retryTransport retries requests that failed because of rate limiting,
+// transient server errors or network errors. Rate limited requests wait for
+// Retry-After or X-RateLimit-Reset; everything else backs off exponentially
+// with jitter. Non-idempotent requests are only retried when GitHub rejected
+// them outright, so a comment is never posted twice.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := rt.base.RoundTrip(req)
+		if resp != nil {
+			lastRate.update(resp)
+		}
+
+		wait, retry := retryDelay(req, resp, err, attempt)
+		// a body that was sent can't be sent again; hand back what GitHub said.
+		if !retry || attempt >= maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if wait >= 5*time.Second {
+			fmt.Fprintf(os.Stderr, "%s %s: retrying in %v\n", req.Method, req.URL.Path, wait.Round(time.Second))
+		}
+
+		if err := retrySleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+
+	return false
+}
+
+func backoff(attempt int) time.Duration {
+	d := time.Second << uint(attempt)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		if req.Context().Err() != nil || !idempotent(req.Method) {
+			return 0, false
+		}
+
+		return backoff(attempt), true
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusForbidden && rateLimited(resp):
+		wait, ok := rateLimitWait(resp)
+		if !ok {
+			wait = backoff(attempt)
+		}
+
+		// a wait this long would look like a hang; let the error surface.
+		if wait > maxRateLimitWait {
+			return 0, false
+		}
+
+		return wait, true
+	case resp.StatusCode == http.StatusBadGateway, resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		if !idempotent(req.Method) {
+			return 0, false
+		}
+
+		return backoff(attempt), true
+	}
+
+	return 0, false
+}
+
+func rateLimited(resp *http.Response) bool {
+	if resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	msg := strings.ToLower(string(body))
+	return strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse detection")
+}
+
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if after := resp.Header.Get("Retry-After"); after != "" {
+		if secs, err := strconv.Atoi(after); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Until(time.Unix(reset, 0)) + time.Second
+			if wait < 0 {
+				wait = time.Second
+			}
+
+			return wait, true
+		}
+	}
+
+	return 0, false
+}
+
+type rateLimitView struct {
+	Resource  string    `json:"resource" yaml:"resource"`
+	Limit     int       `json:"limit" yaml:"limit"`
+	Remaining int       `json:"remaining" yaml:"remaining"`
+	Reset     time.Time `json:"reset" yaml:"reset"`
+}
+
+func rateLimit(ctx *cli.Context) {
+	client := getClient()
+
+	limits, _, err := client.RateLimits(context.Background())
+	if err != nil {
+		exitError(err)
+	}
+
+	views := []rateLimitView{}
+
+	if limits.Core != nil {
+		views = append(views, rateLimitView{"core", limits.Core.Limit, limits.Core.Remaining, limits.Core.Reset.Time})
+	}
+
+	if limits.Search != nil {
+		views = append(views, rateLimitView{"search", limits.Search.Limit, limits.Search.Remaining, limits.Search.Reset.Time})
+	}
+
+	if structuredOutput(ctx) {
+		printStructured(ctx, views)
+		return
+	}
+
+	color.Output = os.Stdout
+
+	for _, view := range views {
+		c := color.New(color.FgGreen)
+		if view.Remaining == 0 {
+			c = color.New(color.FgRed)
+		} else if view.Remaining < view.Limit/10 {
+			c = color.New(color.FgYellow)
+		}
+
+		color.New(color.FgWhite).Printf("%-8s ", view.Resource)
+		c.Printf("%d/%d", view.Remaining, view.Limit)
+		fmt.Printf(" resets at %s\n", view.Reset.Local().Format(time.Kitchen))
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPI answers each request with the next of its responses, repeating the
+// last one, and records what it was sent.
+type fakeAPI struct {
+	mutex     sync.Mutex
+	responses []func(w http.ResponseWriter)
+	bodies    []string
+}
+
+func (fa *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	fa.mutex.Lock()
+	i := len(fa.bodies)
+	fa.bodies = append(fa.bodies, string(body))
+	if i >= len(fa.responses) {
+		i = len(fa.responses) - 1
+	}
+	respond := fa.responses[i]
+	fa.mutex.Unlock()
+
+	respond(w)
+}
+
+func (fa *fakeAPI) requests() int {
+	return len(fa.sent())
+}
+
+// sent is the bodies of the requests so far.
+func (fa *fakeAPI) sent() []string {
+	fa.mutex.Lock()
+	defer fa.mutex.Unlock()
+
+	return append([]string(nil), fa.bodies...)
+}
+
+func status(code int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+
+		w.WriteHeader(code)
+		io.WriteString(w, `{"message": "`+http.StatusText(code)+`"}`)
+	}
+}
+
+// withFakeSleep records the waits retryTransport asks for instead of
+// sleeping through them.
+func withFakeSleep(t *testing.T) *[]time.Duration {
+	waits := []time.Duration{}
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	t.Cleanup(func() { retrySleep = sleepContext })
+
+	return &waits
+}
+
+func roundTrip(t *testing.T, fa *fakeAPI, req *http.Request) *http.Response {
+	t.Helper()
+
+	server := httptest.NewServer(fa)
+	t.Cleanup(server.Close)
+
+	u, _ := req.URL.Parse(server.URL + req.URL.Path)
+	req.URL = u
+	req.Host = u.Host
+
+	resp, err := (&retryTransport{base: http.DefaultTransport}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestRetryAfter(t *testing.T) {
+	waits := withFakeSleep(t)
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){
+		status(http.StatusTooManyRequests, "Retry-After", "7"),
+		status(http.StatusOK),
+	}}
+
+	req, _ := http.NewRequest("GET", "/repos/o/r/pulls", nil)
+	resp := roundTrip(t, fa, req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want 200", resp.StatusCode)
+	}
+
+	if fa.requests() != 2 {
+		t.Fatalf("got %d requests, want 2", fa.requests())
+	}
+
+	if len(*waits) != 1 || (*waits)[0] != 7*time.Second {
+		t.Fatalf("got waits %v, want [7s]", *waits)
+	}
+}
+
+func TestRateLimitReset(t *testing.T) {
+	waits := withFakeSleep(t)
+	reset := time.Now().Add(20 * time.Second).Unix()
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){
+		status(http.StatusForbidden, "X-RateLimit-Limit", "5000", "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(reset, 10)),
+		status(http.StatusOK, "X-RateLimit-Limit", "5000", "X-RateLimit-Remaining", "4999", "X-RateLimit-Reset", strconv.FormatInt(reset, 10)),
+	}}
+
+	// the POST is retried too: GitHub refused it without acting on it.
+	req, _ := http.NewRequest("POST", "/repos/o/r/issues", strings.NewReader(`{"title":"t"}`))
+	resp := roundTrip(t, fa, req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want 200", resp.StatusCode)
+	}
+
+	if bodies := fa.sent(); len(bodies) != 2 || bodies[1] != `{"title":"t"}` {
+		t.Fatalf("got bodies %q, want the body sent twice", bodies)
+	}
+
+	if len(*waits) != 1 || (*waits)[0] < 15*time.Second || (*waits)[0] > 22*time.Second {
+		t.Fatalf("got waits %v, want about 21s until the reset", *waits)
+	}
+
+	lastRate.mutex.Lock()
+	remaining := lastRate.remaining
+	lastRate.mutex.Unlock()
+
+	if remaining != 4999 {
+		t.Fatalf("got %d remaining, want the last response's 4999", remaining)
+	}
+}
+
+func TestSecondaryRateLimit(t *testing.T) {
+	withFakeSleep(t)
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){
+		func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"message": "You have exceeded a secondary rate limit."}`)
+		},
+		status(http.StatusOK),
+	}}
+
+	req, _ := http.NewRequest("GET", "/user", nil)
+	if resp := roundTrip(t, fa, req); resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestForbiddenIsNotRetried(t *testing.T) {
+	withFakeSleep(t)
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){status(http.StatusForbidden)}}
+
+	req, _ := http.NewRequest("GET", "/repos/o/r", nil)
+	resp := roundTrip(t, fa, req)
+
+	if resp.StatusCode != http.StatusForbidden || fa.requests() != 1 {
+		t.Fatalf("got status %d after %d requests, want 403 after 1", resp.StatusCode, fa.requests())
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "Forbidden") {
+		t.Fatalf("got body %q, want the original message", body)
+	}
+}
+
+func TestServerErrorBackoff(t *testing.T) {
+	waits := withFakeSleep(t)
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){status(http.StatusBadGateway)}}
+
+	req, _ := http.NewRequest("GET", "/repos/o/r/pulls", nil)
+	resp := roundTrip(t, fa, req)
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("got status %d, want the last 502", resp.StatusCode)
+	}
+
+	if fa.requests() != maxRetries+1 {
+		t.Fatalf("got %d requests, want %d", fa.requests(), maxRetries+1)
+	}
+
+	for i, wait := range *waits {
+		max := time.Second << uint(i)
+		if max > maxBackoff {
+			max = maxBackoff
+		}
+
+		if wait < max/2 || wait > max {
+			t.Fatalf("wait %d was %v, want between %v and %v", i, wait, max/2, max)
+		}
+	}
+}
+
+func TestServerErrorRecovers(t *testing.T) {
+	withFakeSleep(t)
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){
+		status(http.StatusServiceUnavailable),
+		status(http.StatusGatewayTimeout),
+		status(http.StatusOK),
+	}}
+
+	req, _ := http.NewRequest("DELETE", "/repos/o/r/labels/bug", nil)
+	if resp := roundTrip(t, fa, req); resp.StatusCode != http.StatusOK || fa.requests() != 3 {
+		t.Fatalf("got status %d after %d requests, want 200 after 3", resp.StatusCode, fa.requests())
+	}
+}
+
+func TestNonIdempotentServerError(t *testing.T) {
+	withFakeSleep(t)
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){status(http.StatusBadGateway), status(http.StatusCreated)}}
+
+	req, _ := http.NewRequest("POST", "/repos/o/r/issues/1/comments", strings.NewReader(`{"body":"hi"}`))
+	resp := roundTrip(t, fa, req)
+
+	if resp.StatusCode != http.StatusBadGateway || fa.requests() != 1 {
+		t.Fatalf("got status %d after %d requests, want 502 after 1", resp.StatusCode, fa.requests())
+	}
+}
+
+func TestConsumedBodyIsNotRetried(t *testing.T) {
+	waits := withFakeSleep(t)
+	fa := &fakeAPI{responses: []func(http.ResponseWriter){
+		status(http.StatusTooManyRequests, "Retry-After", "1"),
+		status(http.StatusCreated),
+	}}
+
+	// a bare reader leaves GetBody unset, so the body can only be sent once.
+	req, _ := http.NewRequest("POST", "/repos/o/r/issues", ioutil.NopCloser(strings.NewReader(`{"title":"t"}`)))
+	resp := roundTrip(t, fa, req)
+
+	if resp.StatusCode != http.StatusTooManyRequests || fa.requests() != 1 || len(*waits) != 0 {
+		t.Fatalf("got status %d after %d requests and waits %v, want 429 after 1 and no wait", resp.StatusCode, fa.requests(), *waits)
+	}
+}
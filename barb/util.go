@@ -2,12 +2,12 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
-	"regexp"
 	"strings"
 
 	"github.com/docker/docker/pkg/term"
@@ -17,26 +17,103 @@ import (
 	"golang.org/x/oauth2"
 )
 
-var urlRegexp = regexp.MustCompile(`(https://|git@)github.com[:/](\S+)`)
-
 func exitError(err error) {
 	fmt.Fprintln(os.Stderr, err.Error())
 	os.Exit(1)
 }
 
 func repo() (string, string, error) {
-	content, err := exec.Command("git", "config", "--get", "remote.origin.url").CombinedOutput()
+	r, err := resolveRepo()
 	if err != nil {
 		return "", "", err
 	}
 
-	match := urlRegexp.FindStringSubmatch(string(content))
-	if len(match) != 3 {
-		return "", "", errors.New("invalid url in origin remote")
+	return r.Owner, r.Name, nil
+}
+
+func gitTopLevel() string {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// runGit runs git with its output on stderr, so it can't mix with ours.
+func runGit(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
+
+func gitOutput(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// page shows r through $PAGER, or less, when stdout is a terminal.
+func page(r io.Reader) error {
+	if !term.IsTerminal(os.Stdout.Fd()) {
+		_, err := io.Copy(os.Stdout, r)
+		return err
+	}
+
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = r
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
 	}
 
-	parts := strings.Split(match[2], "/")
-	return parts[0], parts[1], nil
+	return cmd.Run()
+}
+
+// editText opens $EDITOR on a temporary file holding initial and returns
+// what the user saved.
+func editText(initial string) (string, error) {
+	f, err := ioutil.TempFile("", "barb-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write([]byte(initial)); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	if err := runProgram(os.Getenv("EDITOR"), f.Name()); err != nil {
+		return "", err
+	}
+
+	content, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
 }
 
 func line() {
@@ -48,14 +125,67 @@ func line() {
 	color.New(color.FgYellow, color.Bold).Println(strings.Repeat("-", int(size.Width)))
 }
 
+func currentHost() string {
+	r, err := resolveRepo()
+	if err != nil {
+		return defaultHost
+	}
+
+	return r.Host
+}
+
+// isNotFound reports whether err is the API answering 404.
+func isNotFound(err error) bool {
+	e, ok := err.(*github.ErrorResponse)
+	return ok && e.Response != nil && e.Response.StatusCode == http.StatusNotFound
+}
+
 func getClient() *github.Client {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+	client, err := newClient(lookupHost(currentHost()))
+	if err != nil {
+		exitError(err)
+	}
+
+	return client
+}
+
+func newClient(hc *hostConfig) (*github.Client, error) {
+	httpClient, err := authHTTPClient(hc)
+	if err != nil {
+		return nil, err
+	}
+
+	return hc.githubClient(httpClient)
+}
+
+// authHTTPClient is the HTTP client behind newClient, which sends the
+// host's credentials with every request.
+func authHTTPClient(hc *hostConfig) (*http.Client, error) {
+	httpClient, err := hc.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+
+	if hc.AppID != "" {
+		// every JWT would make a cache key of its own.
+		plain, err := hc.uncachedHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+
+		ts, err := newAppTokenSource(hc, plain)
+		if err != nil {
+			return nil, err
+		}
+
+		httpClient = oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, ts))
+	} else if hc.Token != "" {
+		httpClient = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: hc.Token}))
+	}
 
-	return github.NewClient(tc)
+	return httpClient, nil
 }
 
 func runProgram(command ...string) error {
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/fatih/color"
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+// pollInterval is how often watch-hooks and merge --when-green look at a PR
+// by default.
+const pollInterval = 30 * time.Second
+
+// watch-hooks exits with one of these, so scripts can tell a red build from
+// one that never finished.
+const (
+	watchSucceeded   = 0
+	watchFailed      = 1
+	watchTimedOut    = 2
+	watchInterrupted = 130
+)
+
+// pollPR calls check with a fresh copy of the pull request every interval
+// until it returns true, returns an error, or ctx is done.
+func pollPR(ctx context.Context, client *github.Client, owner, repo string, num int, interval time.Duration, check func(*github.PullRequest) (bool, error)) error {
+	for {
+		pr, _, err := client.PullRequests.Get(ctx, owner, repo, num)
+		if err != nil {
+			return err
+		}
+
+		done, err := check(pr)
+		if err != nil || done {
+			return err
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+type watchedPR struct {
+	num    int
+	url    string
+	state  string
+	checks []checkView
+	err    error
+	done   bool
+}
+
+func (w *watchedPR) refresh(ctx context.Context, client *github.Client, owner, repo string) {
+	pr, _, err := client.PullRequests.Get(ctx, owner, repo, w.num)
+	if err == nil {
+		w.url = pr.GetHTMLURL()
+		w.checks, err = headChecks(ctx, client, owner, repo, pr.Head.GetSHA())
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		w.err = err
+		w.state = "error"
+		w.done = true
+		return
+	}
+
+	w.state = overallState(w.checks)
+	w.done = w.state != "pending"
+}
+
+// stateMark is the symbol and color a check is drawn with.
+func stateMark(state string) (string, *color.Color) {
+	switch stateClass(state) {
+	case "success":
+		return "✓", color.New(color.FgGreen)
+	case "pending":
+		return "•", color.New(color.FgYellow)
+	}
+
+	return "✗", color.New(color.FgRed)
+}
+
+func (w *watchedPR) lines() []string {
+	finished := 0
+	for _, check := range w.checks {
+		if stateClass(check.State) != "pending" {
+			finished++
+		}
+	}
+
+	mark, c := stateMark(w.state)
+	if w.state == "none" {
+		mark, c = "-", color.New(color.FgWhite)
+	}
+
+	lines := []string{c.Sprintf("%s PR #%d: %s (%d/%d checks finished)", mark, w.num, w.state, finished, len(w.checks))}
+	if w.err != nil {
+		lines = append(lines, color.New(color.FgRed).Sprintf("    %v", w.err))
+	}
+
+	for _, check := range w.checks {
+		mark, c := stateMark(check.State)
+		lines = append(lines, c.Sprintf("    %s %s: %s", mark, check.Name, check.State))
+	}
+
+	return lines
+}
+
+// notify tells the user a PR finished: "bell" rings the terminal bell,
+// "desktop" raises a desktop notification, and anything else is run as a
+// shell command with BARB_PR_NUMBER, BARB_PR_STATE and BARB_PR_URL set.
+func notify(spec string, w *watchedPR) error {
+	text := fmt.Sprintf("PR #%d: %s", w.num, w.state)
+
+	switch spec {
+	case "":
+		return nil
+	case "bell":
+		fmt.Fprint(os.Stderr, "\a")
+		return nil
+	case "desktop":
+		if runtime.GOOS == "darwin" {
+			return exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title \"barb\"", text)).Run()
+		}
+
+		return exec.Command("notify-send", "barb", text).Run()
+	}
+
+	cmd := exec.Command("sh", "-c", spec)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"BARB_PR_NUMBER="+strconv.Itoa(w.num),
+		"BARB_PR_STATE="+w.state,
+		"BARB_PR_URL="+w.url,
+	)
+
+	return cmd.Run()
+}
+
+func watch(ctx *cli.Context) error {
+	client := getClient()
+
+	args := ctx.Args()
+	if len(args) < 1 {
+		exitError(errors.New("invalid arguments"))
+	}
+
+	owner, repo, err := repo()
+	if err != nil {
+		exitError(err)
+	}
+
+	interval := ctx.Duration("interval")
+	if interval < time.Second {
+		exitError(fmt.Errorf("invalid --interval %v", interval))
+	}
+
+	watched := []*watchedPR{}
+	nums := []int{}
+	for _, arg := range args {
+		num, err := strconv.Atoi(arg)
+		if err != nil {
+			exitError(err)
+		}
+
+		watched = append(watched, &watchedPR{num: num, state: "pending"})
+		nums = append(nums, num)
+	}
+
+	// checks on a draft are real, but green does not mean it can be merged.
+	if drafts, err := pullDrafts(client, owner, repo, nums); err == nil {
+		for _, num := range nums {
+			if drafts[num] {
+				fmt.Fprintf(os.Stderr, "warning: PR %d is a draft and cannot be merged until it is marked ready\n", num)
+			}
+		}
+	}
+
+	pollCtx, cancel := interruptContext()
+	defer cancel()
+
+	if timeout := ctx.Duration("timeout"); timeout > 0 {
+		pollCtx, cancel = context.WithTimeout(pollCtx, timeout)
+		defer cancel()
+	}
+
+	structured := structuredOutput(ctx)
+	live := !structured && term.IsTerminal(os.Stdout.Fd())
+	color.Output = os.Stdout
+
+	if !structured {
+		color.New(color.FgHiWhite).Printf("Monitoring PRs for %s/%s (ids: %v); will output as things finish.\n", owner, repo, args)
+	}
+
+	drawn := 0
+	seen := map[string]string{}
+
+	for {
+		remaining := 0
+
+		for _, w := range watched {
+			if w.done {
+				continue
+			}
+
+			w.refresh(pollCtx, client, owner, repo)
+			if pollCtx.Err() != nil {
+				break
+			}
+
+			if w.done {
+				if err := notify(ctx.String("notify"), w); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: notify: %v\n", err)
+				}
+			} else {
+				remaining++
+			}
+		}
+
+		if pollCtx.Err() != nil {
+			break
+		}
+
+		switch {
+		case live:
+			lines := []string{}
+			for _, w := range watched {
+				lines = append(lines, w.lines()...)
+			}
+
+			// redraw the previous block in place.
+			if drawn > 0 {
+				fmt.Printf("\033[%dA", drawn)
+			}
+
+			for _, l := range lines {
+				fmt.Printf("\033[2K%s\n", l)
+			}
+
+			drawn = len(lines)
+		case !structured:
+			for _, w := range watched {
+				for _, check := range w.checks {
+					key := fmt.Sprintf("%d/%s/%s", w.num, check.Kind, check.Name)
+					if seen[key] != check.State {
+						seen[key] = check.State
+						mark, c := stateMark(check.State)
+						c.Printf("%s PR #%d %s: %s\n", mark, w.num, check.Name, check.State)
+					}
+				}
+
+				if w.done && seen[strconv.Itoa(w.num)] == "" {
+					seen[strconv.Itoa(w.num)] = w.state
+					fmt.Printf("Finished: %d (%s)\n", w.num, w.state)
+					fmt.Printf("Remaining: %d\n", remaining)
+				}
+			}
+		}
+
+		if remaining == 0 {
+			break
+		}
+
+		if sleepContext(pollCtx, interval) != nil {
+			break
+		}
+	}
+
+	code := watchSucceeded
+	results := []watchResultView{}
+
+	for _, w := range watched {
+		switch {
+		case !w.done:
+			w.state = "pending"
+			if code == watchSucceeded {
+				code = watchTimedOut
+			}
+		case stateClass(w.state) == "failure":
+			code = watchFailed
+		}
+
+		results = append(results, watchResultView{Number: w.num, State: w.state, Checks: w.checks})
+	}
+
+	if code == watchTimedOut && errors.Is(pollCtx.Err(), context.Canceled) {
+		code = watchInterrupted
+	}
+
+	if structured {
+		printStructured(ctx, results)
+	} else if code == watchTimedOut {
+		fmt.Fprintln(os.Stderr, "Timed out waiting for checks to finish.")
+	}
+
+	if code != watchSucceeded {
+		return cli.NewExitError("", code)
+	}
+
+	return nil
+}